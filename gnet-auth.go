@@ -0,0 +1,24 @@
+package gnet
+
+import (
+	"net/http"
+	"io"
+)
+
+// authenticator sets the credentials of a request, and decides whether
+// the request should be resent with new credentials after 401.
+type authenticator interface {
+	authorize(req *http.Request, body io.ReadSeeker) error
+	retry(resp *http.Response) bool
+}
+
+// an authenticator for every call, nil if no authentication options given.
+func (option *Options) newAuthenticator() authenticator {
+	if option.tokenSource != nil {
+		return &bearerAuth{ts: option.tokenSource}
+	}
+	if option.digestAuth {
+		return newDigestAuth(option.daUser, option.daPasswd)
+	}
+	return nil
+}
@@ -0,0 +1,520 @@
+package gnet
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/json"
+	"context"
+	"path/filepath"
+	"net/http"
+	"strconv"
+	"strings"
+	"bytes"
+	"sync"
+	"time"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ---- HTTP cache (RFC 9111) ----
+// a private cache of the GET responses enabled by WithCache(). The cache status of a response
+// is got with GetCacheStatus().
+
+const (
+	HeaderCacheStatus = "X-Cache-Status"
+
+	CacheHit = "HIT"                 // fresh response from the cache
+	CacheMiss = "MISS"               // response from the server
+	CacheRevalidated = "REVALIDATED" // stored response validated by the server with 304
+	CacheStale = "STALE"             // stale response, revalidated in background (stale-while-revalidate)
+)
+
+// storage of the cache entries
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte)
+	Delete(key string)
+}
+
+// cache status of resp, "" if the cache is not used.
+func GetCacheStatus(resp *http.Response) string {
+	if resp == nil {
+		return ""
+	}
+	return resp.Header.Get(HeaderCacheStatus)
+}
+
+// ---- in-memory LRU cache ----
+const defaultMemoryCacheEntries = 1024
+
+type memoryCache struct {
+	mu sync.Mutex
+	maxEntries int
+	ll *list.List
+	items map[string]*list.Element
+}
+
+type memoryCacheItem struct {
+	key string
+	value []byte
+}
+
+// NewMemoryCache keeps maxEntries entries at most, the least recently used one is evicted first.
+func NewMemoryCache(maxEntries int) Cache {
+	if maxEntries <= 0 {
+		maxEntries = defaultMemoryCacheEntries
+	}
+	return &memoryCache{maxEntries: maxEntries, ll: list.New(), items: map[string]*list.Element{}}
+}
+
+func (c *memoryCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[key]; ok {
+		c.ll.MoveToFront(e)
+		return e.Value.(*memoryCacheItem).value, true
+	}
+	return nil, false
+}
+
+func (c *memoryCache) Set(key string, value []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[key]; ok {
+		c.ll.MoveToFront(e)
+		e.Value.(*memoryCacheItem).value = value
+		return
+	}
+	c.items[key] = c.ll.PushFront(&memoryCacheItem{key: key, value: value})
+	for c.ll.Len() > c.maxEntries {
+		e := c.ll.Back()
+		c.ll.Remove(e)
+		delete(c.items, e.Value.(*memoryCacheItem).key)
+	}
+}
+
+func (c *memoryCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[key]; ok {
+		c.ll.Remove(e)
+		delete(c.items, key)
+	}
+}
+
+// ---- on-disk cache ----
+type diskCache struct {
+	dir string
+}
+
+// NewDiskCache stores every entry as a file under dir, which is created if not existing.
+func NewDiskCache(dir string) (Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &diskCache{dir: dir}, nil
+}
+
+func (c *diskCache) path(key string) string {
+	return filepath.Join(c.dir, fmt.Sprintf("%x", sha256.Sum256([]byte(key))))
+}
+
+func (c *diskCache) Get(key string) ([]byte, bool) {
+	value, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	return value, true
+}
+
+func (c *diskCache) Set(key string, value []byte) {
+	// written to a temp file and renamed, so a partial entry is never read
+	fp, err := os.CreateTemp(c.dir, ".tmp-")
+	if err != nil {
+		return
+	}
+	_, err = fp.Write(value)
+	if e := fp.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Rename(fp.Name(), c.path(key))
+	}
+	if err != nil {
+		os.Remove(fp.Name())
+	}
+}
+
+func (c *diskCache) Delete(key string) {
+	os.Remove(c.path(key))
+}
+
+// ---- cache entry ----
+type cacheEntry struct {
+	RequestTime  time.Time         `json:"requestTime"`
+	ResponseTime time.Time         `json:"responseTime"`
+	Vary         map[string]string `json:"vary,omitempty"` // request headers named by Vary
+	Status       int               `json:"status"`
+	Header       http.Header       `json:"header"`
+	Body         []byte            `json:"body"`
+}
+
+// status codes cacheable by default, RFC 9110 15.1
+var heuristicallyCacheable = map[int]bool{
+	200: true, 203: true, 204: true, 300: true, 301: true, 308: true,
+	404: true, 405: true, 410: true, 414: true, 501: true,
+}
+
+func cacheKey(req *http.Request) string {
+	return http.MethodGet + " " + req.URL.String()
+}
+
+// directives of Cache-Control, the names are in lower case.
+func parseCacheControl(header http.Header) map[string]string {
+	cc := map[string]string{}
+	for _, line := range header.Values("Cache-Control") {
+		for _, d := range strings.Split(line, ",") {
+			d = strings.TrimSpace(d)
+			if len(d) == 0 {
+				continue
+			}
+			name, value := d, ""
+			if pos := strings.IndexByte(d, '='); pos >= 0 {
+				name, value = d[:pos], strings.Trim(strings.TrimSpace(d[pos+1:]), `"`)
+			}
+			cc[strings.ToLower(strings.TrimSpace(name))] = value
+		}
+	}
+	return cc
+}
+
+func ccSeconds(cc map[string]string, name string) (time.Duration, bool) {
+	v, ok := cc[name]
+	if !ok {
+		return 0, false
+	}
+	secs, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || secs < 0 {
+		return 0, false
+	}
+	return time.Duration(secs) * time.Second, true
+}
+
+func varyNames(header http.Header) []string {
+	var names []string
+	for _, line := range header.Values("Vary") {
+		for _, name := range strings.Split(line, ",") {
+			if name = strings.TrimSpace(name); len(name) > 0 {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	return names
+}
+
+func (e *cacheEntry) matchVary(req *http.Request) bool {
+	for name, value := range e.Vary {
+		if strings.Join(req.Header.Values(name), ",") != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *cacheEntry) date() time.Time {
+	if t, err := http.ParseTime(e.Header.Get("Date")); err == nil {
+		return t
+	}
+	return e.ResponseTime
+}
+
+// freshness lifetime, RFC 9111 4.2.1
+func (e *cacheEntry) freshnessLifetime(cc map[string]string) time.Duration {
+	if maxAge, ok := ccSeconds(cc, "max-age"); ok {
+		return maxAge
+	}
+	if expires := e.Header.Get("Expires"); len(expires) > 0 {
+		t, err := http.ParseTime(expires)
+		if err != nil {
+			// an invalid Expires means already expired
+			return 0
+		}
+		return t.Sub(e.date())
+	}
+	if lastModified, err := http.ParseTime(e.Header.Get("Last-Modified")); err == nil && heuristicallyCacheable[e.Status] {
+		if d := e.date().Sub(lastModified); d > 0 {
+			return d / 10
+		}
+	}
+	return 0
+}
+
+// current age, RFC 9111 4.2.3
+func (e *cacheEntry) age(now time.Time) time.Duration {
+	apparentAge := e.ResponseTime.Sub(e.date())
+	if apparentAge < 0 {
+		apparentAge = 0
+	}
+	var ageValue time.Duration
+	if secs, err := strconv.ParseInt(e.Header.Get("Age"), 10, 64); err == nil && secs > 0 {
+		ageValue = time.Duration(secs) * time.Second
+	}
+	correctedAgeValue := ageValue + e.ResponseTime.Sub(e.RequestTime)
+	if correctedAgeValue > apparentAge {
+		apparentAge = correctedAgeValue
+	}
+	return apparentAge + now.Sub(e.ResponseTime)
+}
+
+func (e *cacheEntry) response(req *http.Request, age time.Duration, cacheStatus string) *http.Response {
+	header := e.Header.Clone()
+	header.Set("Age", strconv.FormatInt(int64(age / time.Second), 10))
+	header.Set(HeaderCacheStatus, cacheStatus)
+	return newMemResponse(req, e.Status, header, e.Body)
+}
+
+// ---- caching transport ----
+type cacheTransport struct {
+	cache Cache
+	rt http.RoundTripper
+	signed bool // the requests carry credentials of Signer
+	timeout time.Duration // of the revalidation in background
+}
+
+// stale responses being revalidated in background
+var revalidating = &sync.Map{}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		resp, err := t.rt.RoundTrip(req)
+		if err == nil && !isSafeMethod(req.Method) && resp.StatusCode < 400 {
+			// the stored response is invalidated by an unsafe request, RFC 9111 4.4
+			t.cache.Delete(cacheKey(req))
+		}
+		return resp, err
+	}
+
+	reqCC := parseCacheControl(req.Header)
+	if _, ok := reqCC["no-store"]; ok || len(req.Header.Get("Range")) > 0 || hasConditions(req.Header) {
+		return t.rt.RoundTrip(req)
+	}
+	_, noCache := reqCC["no-cache"]
+	if len(req.Header.Values("Cache-Control")) == 0 && strings.Contains(strings.ToLower(req.Header.Get("Pragma")), "no-cache") {
+		noCache = true
+	}
+
+	key := cacheKey(req)
+	entry := t.load(key, req)
+	if entry != nil && !noCache {
+		now := time.Now()
+		cc := parseCacheControl(entry.Header)
+		lifetime, age := entry.freshnessLifetime(cc), entry.age(now)
+		if maxAge, ok := ccSeconds(reqCC, "max-age"); ok && maxAge < lifetime {
+			lifetime = maxAge
+		}
+		_, respNoCache := cc["no-cache"]
+		_, mustRevalidate := cc["must-revalidate"]
+		if !respNoCache {
+			if age < lifetime {
+				return entry.response(req, age, CacheHit), nil
+			}
+			if swr, ok := ccSeconds(cc, "stale-while-revalidate"); ok && !mustRevalidate && age < lifetime + swr {
+				t.revalidate(key, req, entry)
+				return entry.response(req, age, CacheStale), nil
+			}
+		}
+	}
+	if _, ok := reqCC["only-if-cached"]; ok {
+		return newMemResponse(req, http.StatusGatewayTimeout, http.Header{HeaderCacheStatus: {CacheMiss}}, nil), nil
+	}
+
+	return t.fetch(key, req, entry)
+}
+
+// send req, conditionally if entry is not nil, and store the response.
+func (t *cacheTransport) fetch(key string, req *http.Request, entry *cacheEntry) (*http.Response, error) {
+	r := req
+	if entry != nil {
+		etag, lastModified := entry.Header.Get("ETag"), entry.Header.Get("Last-Modified")
+		if len(etag) > 0 || len(lastModified) > 0 {
+			r = req.Clone(req.Context())
+			if len(etag) > 0 {
+				r.Header.Set("If-None-Match", etag)
+			}
+			if len(lastModified) > 0 {
+				r.Header.Set("If-Modified-Since", lastModified)
+			}
+		}
+	}
+
+	requestTime := time.Now()
+	resp, err := t.rt.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+	responseTime := time.Now()
+
+	if resp.StatusCode == http.StatusNotModified && entry != nil && r != req {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		// the stored headers are updated with the 304, RFC 9111 4.3.4
+		for k, v := range resp.Header {
+			switch k {
+			case "Content-Length", "Content-Encoding", "Transfer-Encoding", "Content-Range", "Set-Cookie":
+			default:
+				entry.Header[k] = v
+			}
+		}
+		entry.RequestTime, entry.ResponseTime = requestTime, responseTime
+		t.store(key, entry)
+		revalidated := entry.response(req, entry.age(responseTime), CacheRevalidated)
+		if cookies := resp.Header.Values("Set-Cookie"); len(cookies) > 0 {
+			// the cookies are for this caller only
+			revalidated.Header["Set-Cookie"] = cookies
+		}
+		return revalidated, nil
+	}
+
+	if !storable(req, resp, t.signed) {
+		resp.Header.Set(HeaderCacheStatus, CacheMiss)
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	e := &cacheEntry{
+		RequestTime: requestTime,
+		ResponseTime: responseTime,
+		Status: resp.StatusCode,
+		Header: resp.Header.Clone(),
+		Body: body,
+	}
+	// the cookies are for this caller only, they are not replayed by the stored response
+	e.Header.Del("Set-Cookie")
+	if names := varyNames(resp.Header); len(names) > 0 {
+		e.Vary = make(map[string]string, len(names))
+		for _, name := range names {
+			e.Vary[name] = strings.Join(req.Header.Values(name), ",")
+		}
+	}
+	t.store(key, e)
+
+	resp.Header.Set(HeaderCacheStatus, CacheMiss)
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	return resp, nil
+}
+
+// revalidate the stale entry in background, once at a time for the key.
+func (t *cacheTransport) revalidate(key string, req *http.Request, entry *cacheEntry) {
+	inflight := fmt.Sprintf("%p|%s", t.cache, key)
+	if _, loaded := revalidating.LoadOrStore(inflight, true); loaded {
+		return
+	}
+	// the context of req may be canceled once the stale response is returned,
+	// the revalidation is bounded by the timeout of the request instead
+	ctx, cancel := context.WithTimeout(detachedContext{req.Context()}, t.timeout)
+	r := req.Clone(ctx)
+	e := *entry
+	e.Header = entry.Header.Clone()
+	go func() {
+		defer revalidating.Delete(inflight)
+		defer cancel()
+		if resp, err := t.fetch(key, r, &e); err == nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+	}()
+}
+
+func (t *cacheTransport) load(key string, req *http.Request) *cacheEntry {
+	value, ok := t.cache.Get(key)
+	if !ok {
+		return nil
+	}
+	var e cacheEntry
+	if err := json.Unmarshal(value, &e); err != nil {
+		t.cache.Delete(key)
+		return nil
+	}
+	if !e.matchVary(req) {
+		return nil
+	}
+	return &e
+}
+
+func (t *cacheTransport) store(key string, e *cacheEntry) {
+	value, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	t.cache.Set(key, value)
+}
+
+// RFC 9111 3
+func storable(req *http.Request, resp *http.Response, signed bool) bool {
+	cc := parseCacheControl(resp.Header)
+	if _, ok := cc["no-store"]; ok {
+		return false
+	}
+	if signed || len(req.Header.Get("Authorization")) > 0 || len(req.Header.Get("Cookie")) > 0 {
+		// the cache may be shared by the callers of different credentials or sessions, RFC 9111 3.5
+		_, public := cc["public"]
+		_, sMaxAge := cc["s-maxage"]
+		_, mustRevalidate := cc["must-revalidate"]
+		if !public && !sMaxAge && !mustRevalidate {
+			return false
+		}
+	}
+	for _, name := range varyNames(resp.Header) {
+		if name == "*" {
+			return false
+		}
+	}
+	if resp.StatusCode == http.StatusPartialContent || resp.StatusCode == http.StatusNotModified {
+		return false
+	}
+	if _, ok := cc["max-age"]; ok {
+		return true
+	}
+	if _, ok := cc["public"]; ok {
+		return true
+	}
+	if len(resp.Header.Get("Expires")) > 0 {
+		return true
+	}
+	// useless to store without validators, the heuristic freshness needs Last-Modified
+	return heuristicallyCacheable[resp.StatusCode] && (len(resp.Header.Get("ETag")) > 0 || len(resp.Header.Get("Last-Modified")) > 0)
+}
+
+func hasConditions(header http.Header) bool {
+	for _, name := range []string{"If-None-Match", "If-Modified-Since", "If-Match", "If-Unmodified-Since", "If-Range"} {
+		if len(header.Get(name)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, "PROPFIND":
+		return true
+	default:
+		return false
+	}
+}
+
+// values of the parent context without its cancellation and deadline
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{} { return nil }
+func (detachedContext) Err() error { return nil }
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
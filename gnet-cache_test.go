@@ -0,0 +1,264 @@
+package gnet
+
+import (
+	"testing"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"strings"
+	"time"
+	"fmt"
+	"io"
+	"os"
+)
+
+func TestCache(t *testing.T) {
+	var hits int32
+	lastModified := time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&hits, 1)
+		switch r.URL.Path {
+		case "/max-age":
+			w.Header().Set("Cache-Control", "max-age=60")
+		case "/no-store":
+			w.Header().Set("Cache-Control", "no-store, max-age=60")
+		case "/etag":
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("ETag", `"v1"`)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		case "/last-modified":
+			w.Header().Set("Cache-Control", "max-age=0")
+			w.Header().Set("Last-Modified", lastModified)
+			if r.Header.Get("If-Modified-Since") == lastModified {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		case "/vary":
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Vary", "Accept-Language")
+			io.WriteString(w, r.Header.Get("Accept-Language"))
+			return
+		}
+		fmt.Fprintf(w, "%s %d", r.URL.Path, n)
+	}))
+	defer ts.Close()
+
+	cache := NewMemoryCache(0)
+	get := func(path, expected, cacheStatus string, options ...Option) {
+		t.Helper()
+		status, content, resp, err := Http(ts.URL + path, append(options, WithCache(cache))...)
+		if err != nil || status != http.StatusOK || string(content) != expected {
+			t.Fatalf("unexpected %d %s, %v\n", status, content, err)
+		}
+		if s := GetCacheStatus(resp); s != cacheStatus {
+			t.Fatalf("cache status %s of %s expected, got %s\n", cacheStatus, path, s)
+		}
+	}
+
+	get("/max-age", "/max-age 1", CacheMiss)
+	get("/max-age", "/max-age 1", CacheHit)
+	get("/max-age", "/max-age 2", CacheMiss, SetHeader("Cache-Control", "no-cache"))
+	get("/max-age", "/max-age 2", CacheHit)
+
+	get("/no-store", "/no-store 3", CacheMiss)
+	get("/no-store", "/no-store 4", CacheMiss)
+
+	get("/etag", "/etag 5", CacheMiss)
+	get("/etag", "/etag 5", CacheRevalidated)
+	get("/last-modified", "/last-modified 7", CacheMiss)
+	get("/last-modified", "/last-modified 7", CacheRevalidated)
+
+	get("/vary", "en", CacheMiss, SetHeader("Accept-Language", "en"))
+	get("/vary", "en", CacheHit, SetHeader("Accept-Language", "en"))
+	get("/vary", "fr", CacheMiss, SetHeader("Accept-Language", "fr"))
+
+	// the stored response is invalidated by POST
+	if _, _, _, err := Http(ts.URL + "/max-age", M("POST"), WithCache(cache)); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	get("/max-age", "/max-age 12", CacheMiss)
+	if c := atomic.LoadInt32(&hits); c != 12 {
+		t.Fatalf("12 requests expected, got %d\n", c)
+	}
+}
+
+func TestCacheStaleWhileRevalidate(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "max-age=0, stale-while-revalidate=60")
+		fmt.Fprintf(w, "%d", n)
+	}))
+	defer ts.Close()
+
+	cache := NewMemoryCache(0)
+	if _, content, resp, err := Http(ts.URL, WithCache(cache)); err != nil || string(content) != "1" || GetCacheStatus(resp) != CacheMiss {
+		t.Fatalf("unexpected %s, %v\n", content, err)
+	}
+	if _, content, resp, err := Http(ts.URL, WithCache(cache)); err != nil || string(content) != "1" || GetCacheStatus(resp) != CacheStale {
+		t.Fatalf("unexpected %s %s, %v\n", content, GetCacheStatus(resp), err)
+	}
+	for i:=0; i<100 && atomic.LoadInt32(&hits) < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	for i:=0; i<100; i++ {
+		if _, content, _, _ := Http(ts.URL, WithCache(cache)); string(content) != "1" {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("the stale response is not revalidated\n")
+}
+
+func TestCacheRevalidateTimeout(t *testing.T) {
+	var hits int32
+	stalled := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) > 1 {
+			// the revalidation stalls
+			<-stalled
+		}
+		w.Header().Set("Cache-Control", "max-age=0, stale-while-revalidate=60")
+		io.WriteString(w, "stale")
+	}))
+	defer ts.Close()
+	defer close(stalled)
+
+	cache := NewMemoryCache(0)
+	timeout := WithTimeoutDuration(200 * time.Millisecond)
+	if _, content, resp, err := Http(ts.URL, WithCache(cache), timeout); err != nil || GetCacheStatus(resp) != CacheMiss {
+		t.Fatalf("unexpected %s, %v\n", content, err)
+	}
+	if _, content, resp, err := Http(ts.URL, WithCache(cache), timeout); err != nil || GetCacheStatus(resp) != CacheStale {
+		t.Fatalf("unexpected %s, %v\n", content, err)
+	}
+
+	prefix := fmt.Sprintf("%p|", cache)
+	revalidatingCount := func() (n int) {
+		revalidating.Range(func(k, _ interface{}) bool {
+			if strings.HasPrefix(k.(string), prefix) {
+				n++
+			}
+			return true
+		})
+		return
+	}
+	for i:=0; i<200 && revalidatingCount() > 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if revalidatingCount() > 0 {
+		t.Fatalf("the stalled revalidation expected to time out\n")
+	}
+}
+
+func TestCacheAuthorization(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&hits, 1)
+		if r.URL.Path == "/public" {
+			w.Header().Set("Cache-Control", "public, max-age=60")
+		} else {
+			w.Header().Set("Cache-Control", "max-age=60")
+		}
+		fmt.Fprintf(w, "%s %d", r.URL.Path, n)
+	}))
+	defer ts.Close()
+
+	cache := NewMemoryCache(0)
+	get := func(path, expected, cacheStatus string) {
+		t.Helper()
+		_, content, resp, err := Http(ts.URL + path, BasicAuth("user", "secret"), WithCache(cache))
+		if err != nil || string(content) != expected || GetCacheStatus(resp) != cacheStatus {
+			t.Fatalf("%s %s of %s expected, got %s %s, %v\n", expected, cacheStatus, path, content, GetCacheStatus(resp), err)
+		}
+	}
+
+	// the responses to the requests with Authorization are stored only if they are public
+	get("/private", "/private 1", CacheMiss)
+	get("/private", "/private 2", CacheMiss)
+	get("/public", "/public 3", CacheMiss)
+	get("/public", "/public 3", CacheHit)
+}
+
+func TestDiskCache(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		io.WriteString(w, "cached on disk")
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "gnet-cache")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for i, cacheStatus := range []string{CacheMiss, CacheHit} {
+		// a new cache of the same dir reads the stored entries
+		cache, err := NewDiskCache(dir)
+		if err != nil {
+			t.Fatalf("%v\n", err)
+		}
+		if _, content, resp, err := Http(ts.URL, WithCache(cache)); err != nil || string(content) != "cached on disk" || GetCacheStatus(resp) != cacheStatus {
+			t.Fatalf("#%d: unexpected %s %s, %v\n", i, content, GetCacheStatus(resp), err)
+		}
+	}
+	if c := atomic.LoadInt32(&hits); c != 1 {
+		t.Fatalf("1 request expected, got %d\n", c)
+	}
+}
+
+func TestMemoryCacheLRU(t *testing.T) {
+	cache := NewMemoryCache(2)
+	cache.Set("a", []byte("a"))
+	cache.Set("b", []byte("b"))
+	cache.Get("a")
+	cache.Set("c", []byte("c"))
+	if _, ok := cache.Get("b"); ok {
+		t.Fatalf("b should be evicted\n")
+	}
+	for _, key := range []string{"a", "c"} {
+		if v, ok := cache.Get(key); !ok || string(v) != key {
+			t.Fatalf("%s expected in cache\n", key)
+		}
+	}
+}
+
+func TestCacheCookie(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&hits, 1)
+		if r.URL.Path == "/public" {
+			w.Header().Set("Cache-Control", "public, max-age=60")
+			w.Header().Set("Set-Cookie", fmt.Sprintf("sid=%d", n))
+		} else {
+			w.Header().Set("Cache-Control", "max-age=60")
+		}
+		fmt.Fprintf(w, "%s %d", r.URL.Path, n)
+	}))
+	defer ts.Close()
+
+	cache := NewMemoryCache(0)
+	get := func(path, expected, cacheStatus, setCookie string, options ...Option) {
+		t.Helper()
+		_, content, resp, err := Http(ts.URL + path, append(options, WithCache(cache))...)
+		if err != nil || string(content) != expected || GetCacheStatus(resp) != cacheStatus {
+			t.Fatalf("%s %s of %s expected, got %s %s, %v\n", expected, cacheStatus, path, content, GetCacheStatus(resp), err)
+		}
+		if sc := resp.Header.Get("Set-Cookie"); sc != setCookie {
+			t.Fatalf("Set-Cookie %q expected, got %q\n", setCookie, sc)
+		}
+	}
+
+	// the responses to the requests with Cookie are stored only if they are public
+	get("/private", "/private 1", CacheMiss, "", SetHeader("Cookie", "sid=a"))
+	get("/private", "/private 2", CacheMiss, "", SetHeader("Cookie", "sid=a"))
+	// Set-Cookie is not replayed by the stored response
+	get("/public", "/public 3", CacheMiss, "sid=3")
+	get("/public", "/public 3", CacheHit, "")
+}
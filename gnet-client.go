@@ -1,7 +1,10 @@
 package gnet
 
 import (
+	"container/list"
 	"net/http"
+	"context"
+	"net"
 	"time"
 	"os"
 	"fmt"
@@ -14,32 +17,36 @@ import (
 const (
 	maxIdleConnsPerHost = 2
 	idleConnTimeout = 60 * time.Second
+	maxPooledClients = 64
 )
 
-func httpClientCreator() func(timeout time.Duration) *http.Client {
-	clientPool := &sync.Map{}
+func httpClientCreator() func(timeout time.Duration, dial *dialConfig) *http.Client {
+	clientPool := newClientPool(maxPooledClients)
 
-	return func(timeout time.Duration) *http.Client {
-		if c, ok := clientPool.Load(timeout); ok {
-			return c.(*http.Client)
+	return func(timeout time.Duration, dial *dialConfig) *http.Client {
+		key := fmt.Sprintf("%d|%s", timeout, dial.key())
+		if c, ok := clientPool.load(key); ok {
+			return c
 		}
 
 		transport := &http.Transport{
 			MaxIdleConnsPerHost: maxIdleConnsPerHost,
 			IdleConnTimeout:     idleConnTimeout,
 		}
+		setDialContext(transport, dial)
 		c := &http.Client{Transport: transport, Timeout: timeout}
-		clientPool.Store(timeout, c)
+		clientPool.store(key, c)
 		return c
 	}
 }
 
-func httpsClientCreator() func(timeout time.Duration) *http.Client {
-	clientPool := &sync.Map{}
+func httpsClientCreator() func(timeout time.Duration, dial *dialConfig) *http.Client {
+	clientPool := newClientPool(maxPooledClients)
 
-	return func(timeout time.Duration) *http.Client {
-		if c, ok := clientPool.Load(timeout); ok {
-			return c.(*http.Client)
+	return func(timeout time.Duration, dial *dialConfig) *http.Client {
+		key := fmt.Sprintf("%d|%s", timeout, dial.key())
+		if c, ok := clientPool.load(key); ok {
+			return c
 		}
 
 		transport := &http.Transport{
@@ -49,25 +56,27 @@ func httpsClientCreator() func(timeout time.Duration) *http.Client {
 			MaxIdleConnsPerHost: maxIdleConnsPerHost,
 			IdleConnTimeout:     idleConnTimeout,
 		}
+		setDialContext(transport, dial)
 
 		c := &http.Client{Transport: transport, Timeout: timeout}
-		clientPool.Store(timeout, c)
+		clientPool.store(key, c)
 		return c
 	}
 }
 
-func httpsClientWithCertFilesCreator() func(certPemFile, keyPemFile string, timeout time.Duration) (*http.Client, error) {
-	clientPool := &sync.Map{}
+func httpsClientWithCertFilesCreator() func(certPemFile, keyPemFile string, timeout time.Duration, dial *dialConfig) (*http.Client, error) {
+	clientPool := newClientPool(maxPooledClients)
 
-	return func(certPemFile, keyPemFile string, timeout time.Duration) (*http.Client, error) {
+	return func(certPemFile, keyPemFile string, timeout time.Duration, dial *dialConfig) (*http.Client, error) {
 		h := md5.New()
 		fmt.Fprintf(h, "%s", certPemFile)
 		fmt.Fprintf(h, "%s", keyPemFile)
 		fmt.Fprintf(h, "%d", timeout)
+		fmt.Fprintf(h, "%s", dial.key())
 		signature := fmt.Sprintf("%x", h.Sum(nil))
 
-		if c, ok := clientPool.Load(signature); ok {
-			return c.(*http.Client), nil
+		if c, ok := clientPool.load(signature); ok {
+			return c, nil
 		}
 
 		cert, err := tls.LoadX509KeyPair(certPemFile, keyPemFile)
@@ -91,26 +100,28 @@ func httpsClientWithCertFilesCreator() func(certPemFile, keyPemFile string, time
 			MaxIdleConnsPerHost: maxIdleConnsPerHost,
 			IdleConnTimeout:     idleConnTimeout,
 		}
+		setDialContext(transport, dial)
 
 		c := &http.Client{Transport: transport, Timeout: timeout}
-		clientPool.Store(signature, c)
+		clientPool.store(signature, c)
 		return c, nil
 	}
 }
 
-func httpsClientWithCertBlocksCreator() func(caCert, certPEMBlock, keyPEMBlock []byte, timeout time.Duration) (*http.Client, error) {
-	clientPool := &sync.Map{}
+func httpsClientWithCertBlocksCreator() func(caCert, certPEMBlock, keyPEMBlock []byte, timeout time.Duration, dial *dialConfig) (*http.Client, error) {
+	clientPool := newClientPool(maxPooledClients)
 
-	return func(caCert, certPEMBlock, keyPEMBlock []byte, timeout time.Duration) (*http.Client, error) {
+	return func(caCert, certPEMBlock, keyPEMBlock []byte, timeout time.Duration, dial *dialConfig) (*http.Client, error) {
 		h := md5.New()
 		h.Write(caCert)
 		h.Write(certPEMBlock)
 		h.Write(keyPEMBlock)
 		fmt.Fprintf(h, "%d", timeout)
+		fmt.Fprintf(h, "%s", dial.key())
 		signature := fmt.Sprintf("%x", h.Sum(nil))
 
-		if c, ok := clientPool.Load(signature); ok {
-			return c.(*http.Client), nil
+		if c, ok := clientPool.load(signature); ok {
+			return c, nil
 		}
 
 		cert, err := tls.X509KeyPair(certPEMBlock, keyPEMBlock)
@@ -136,9 +147,98 @@ func httpsClientWithCertBlocksCreator() func(caCert, certPEMBlock, keyPEMBlock [
 			MaxIdleConnsPerHost: maxIdleConnsPerHost,
 			IdleConnTimeout:     idleConnTimeout,
 		}
+		setDialContext(transport, dial)
 
 		c := &http.Client{Transport: transport, Timeout: timeout}
-		clientPool.Store(signature, c)
+		clientPool.store(signature, c)
 		return c, nil
 	}
 }
+
+// clientPool keeps the recently used clients, e.g. of different timeouts or dial configs.
+// The idle connections of the evicted clients are closed.
+type clientPool struct {
+	mu sync.Mutex
+	max int
+	ll *list.List
+	items map[string]*list.Element
+}
+
+type pooledClient struct {
+	key string
+	c *http.Client
+}
+
+func newClientPool(max int) *clientPool {
+	return &clientPool{max: max, ll: list.New(), items: map[string]*list.Element{}}
+}
+
+func (p *clientPool) load(key string) (*http.Client, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if e, ok := p.items[key]; ok {
+		p.ll.MoveToFront(e)
+		return e.Value.(*pooledClient).c, true
+	}
+	return nil, false
+}
+
+func (p *clientPool) store(key string, c *http.Client) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if e, ok := p.items[key]; ok {
+		e.Value.(*pooledClient).c = c
+		p.ll.MoveToFront(e)
+		return
+	}
+	p.items[key] = p.ll.PushFront(&pooledClient{key: key, c: c})
+	for p.ll.Len() > p.max {
+		e := p.ll.Back()
+		pc := p.ll.Remove(e).(*pooledClient)
+		delete(p.items, pc.key)
+		pc.c.CloseIdleConnections()
+	}
+}
+
+// the default dialer of http.Transport is kept if dial is default
+func setDialContext(transport *http.Transport, dial *dialConfig) {
+	if dial.isDefault() {
+		return
+	}
+	transport.DialContext = dial.dialContext()
+}
+
+// transports dialing unix sockets, pooled by socket path apart from the TCP ones
+var unixTransports = &sync.Map{}
+
+func unixTransport(socketPath string) *http.Transport {
+	if t, ok := unixTransports.Load(socketPath); ok {
+		return t.(*http.Transport)
+	}
+
+	dialer := &net.Dialer{}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", socketPath)
+		},
+		MaxIdleConnsPerHost: maxIdleConnsPerHost,
+		IdleConnTimeout:     idleConnTimeout,
+	}
+	t, _ := unixTransports.LoadOrStore(socketPath, transport)
+	return t.(*http.Transport)
+}
+
+func unixClientCreator() func(socketPath string, timeout time.Duration) *http.Client {
+	clientPool := newClientPool(maxPooledClients)
+
+	return func(socketPath string, timeout time.Duration) *http.Client {
+		key := fmt.Sprintf("%s|%d", socketPath, timeout)
+		if c, ok := clientPool.load(key); ok {
+			return c
+		}
+
+		c := &http.Client{Transport: unixTransport(socketPath), Timeout: timeout}
+		clientPool.store(key, c)
+		return c
+	}
+}
@@ -0,0 +1,201 @@
+package gnet
+
+import (
+	"path/filepath"
+	"strings"
+	"bufio"
+	"sync"
+	"os"
+)
+
+// CredentialProvider supplies the basic auth credentials of a host. host is
+// "hostname:port" if the URL has a port, or hostname only. The built-in providers
+// try "hostname:port" before hostname.
+type CredentialProvider interface {
+	Credentials(host string) (user, password string, ok bool)
+}
+
+type CredentialProviderFunc func(host string) (user, password string, ok bool)
+
+func (f CredentialProviderFunc) Credentials(host string) (user, password string, ok bool) {
+	return f(host)
+}
+
+type Credential struct {
+	User     string
+	Password string
+}
+
+// names of environment variables containing user and password
+type EnvCredential struct {
+	UserVar     string
+	PasswordVar string
+}
+
+func lookupCredentials(providers []CredentialProvider, host string) (user, password string, ok bool) {
+	for _, p := range providers {
+		if user, password, ok = p.Credentials(host); ok {
+			return
+		}
+	}
+	return
+}
+
+// "hostname:port" and hostname
+func hostCandidates(host string) []string {
+	hosts := []string{host}
+	if pos := strings.LastIndexByte(host, ':'); pos > 0 && !strings.HasSuffix(host, "]") {
+		hosts = append(hosts, strings.Trim(host[:pos], "[]"))
+	}
+	return hosts
+}
+
+// find the value of host in m, "*" matches any host
+func lookupHost(host string, m func(string) bool) bool {
+	for _, h := range hostCandidates(host) {
+		if m(h) {
+			return true
+		}
+	}
+	return m("*")
+}
+
+// ---- static credentials ----
+
+// credentials of hosts, "*" matches any host
+func StaticCredentials(credentials map[string]Credential) CredentialProvider {
+	return CredentialProviderFunc(func(host string) (string, string, bool) {
+		var c Credential
+		if !lookupHost(host, func(h string) (ok bool) { c, ok = credentials[h]; return }) {
+			return "", "", false
+		}
+		return c.User, c.Password, true
+	})
+}
+
+// ---- credentials from environment variables ----
+
+// credentials of hosts from environment variables, "*" matches any host
+func EnvCredentials(vars map[string]EnvCredential) CredentialProvider {
+	return CredentialProviderFunc(func(host string) (string, string, bool) {
+		var v EnvCredential
+		if !lookupHost(host, func(h string) (ok bool) { v, ok = vars[h]; return }) {
+			return "", "", false
+		}
+		user, ok := os.LookupEnv(v.UserVar)
+		if !ok {
+			return "", "", false
+		}
+		return user, os.Getenv(v.PasswordVar), true
+	})
+}
+
+// ---- credentials from .netrc ----
+type netrcProvider struct {
+	netrcFile string
+	once sync.Once
+	machines map[string]Credential
+	defaultCred *Credential
+}
+
+// credentials from netrcFile, $NETRC or ~/.netrc is used if netrcFile not given.
+// the file is read at the first lookup.
+func NetrcCredentials(netrcFile ...string) CredentialProvider {
+	p := &netrcProvider{}
+	if len(netrcFile) > 0 {
+		p.netrcFile = netrcFile[0]
+	} else if f, ok := os.LookupEnv("NETRC"); ok {
+		p.netrcFile = f
+	} else if home, err := os.UserHomeDir(); err == nil {
+		p.netrcFile = filepath.Join(home, ".netrc")
+	}
+	return p
+}
+
+func (p *netrcProvider) Credentials(host string) (string, string, bool) {
+	p.once.Do(p.load)
+	for _, h := range hostCandidates(host) {
+		if c, ok := p.machines[h]; ok {
+			return c.User, c.Password, true
+		}
+	}
+	if p.defaultCred != nil {
+		return p.defaultCred.User, p.defaultCred.Password, true
+	}
+	return "", "", false
+}
+
+func (p *netrcProvider) load() {
+	p.machines = map[string]Credential{}
+	if len(p.netrcFile) == 0 {
+		return
+	}
+	fp, err := os.Open(p.netrcFile)
+	if err != nil {
+		return
+	}
+	defer fp.Close()
+
+	var cur *Credential
+	var machine string
+	done := func() {
+		if cur == nil {
+			return
+		}
+		if len(machine) > 0 {
+			if _, ok := p.machines[machine]; !ok {
+				// the first entry wins
+				p.machines[machine] = *cur
+			}
+		} else if p.defaultCred == nil {
+			p.defaultCred = cur
+		}
+		cur = nil
+	}
+
+	scanner := bufio.NewScanner(fp)
+	inMacdef := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if inMacdef {
+			// a macro definition ends with an empty line
+			inMacdef = len(strings.TrimSpace(line)) > 0
+			continue
+		}
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		tokens := strings.Fields(line)
+		for i:=0; i<len(tokens); i++ {
+			next := func() string {
+				if i+1 < len(tokens) {
+					i++
+					return tokens[i]
+				}
+				return ""
+			}
+			switch tokens[i] {
+			case "machine":
+				done()
+				machine, cur = next(), &Credential{}
+			case "default":
+				done()
+				machine, cur = "", &Credential{}
+			case "login":
+				if v := next(); cur != nil {
+					cur.User = v
+				}
+			case "password":
+				if v := next(); cur != nil {
+					cur.Password = v
+				}
+			case "account":
+				next()
+			case "macdef":
+				inMacdef = true
+				i = len(tokens)
+			}
+		}
+	}
+	done()
+}
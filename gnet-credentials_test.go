@@ -0,0 +1,56 @@
+package gnet
+
+import (
+	"testing"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"fmt"
+	"os"
+)
+
+func TestCredentialProviders(t *testing.T) {
+	netrc := filepath.Join(t.TempDir(), "netrc")
+	os.WriteFile(netrc, []byte(`machine 127.0.0.1 login u1 password p1
+macdef init
+machine x login ux
+
+default login ud password pd
+`), 0600)
+	t.Setenv("GNET_TEST_USER", "u2")
+	t.Setenv("GNET_TEST_PASSWORD", "p2")
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, passwd, _ := r.BasicAuth()
+		fmt.Fprintf(w, "%s:%s", user, passwd)
+	}))
+	defer ts.Close()
+	host := strings.TrimPrefix(ts.URL, "http://")
+
+	cases := []struct{
+		provider CredentialProvider
+		expected string
+	}{
+		{NetrcCredentials(netrc), "u1:p1"},
+		{NetrcCredentials(filepath.Join(t.TempDir(), "none")), ":"},
+		{EnvCredentials(map[string]EnvCredential{"*": {"GNET_TEST_USER", "GNET_TEST_PASSWORD"}}), "u2:p2"},
+		{StaticCredentials(map[string]Credential{host: {"u3", "p3"}, "127.0.0.1": {"u4", "p4"}}), "u3:p3"},
+	}
+	for _, c := range cases {
+		_, content, _, err := Http(ts.URL, WithCredentialProvider(c.provider))
+		if err != nil {
+			t.Fatalf("%v\n", err)
+		}
+		if string(content) != c.expected {
+			t.Fatalf("expected %s, got %s\n", c.expected, content)
+		}
+	}
+
+	if u, p, _ := NetrcCredentials(netrc).Credentials("other"); u != "ud" || p != "pd" {
+		t.Fatalf("default of netrc expected, got %s:%s\n", u, p)
+	}
+	if u, _, _ := NetrcCredentials(netrc).Credentials("x"); u == "ux" {
+		t.Fatalf("macdef is expected to be skipped\n")
+	}
+}
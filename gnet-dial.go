@@ -0,0 +1,299 @@
+package gnet
+
+import (
+	"context"
+	"strings"
+	"sort"
+	"sync"
+	"time"
+	"fmt"
+	"net"
+)
+
+// preference of IP address family when dialing
+type IPPreference int
+const (
+	IPAny IPPreference = iota
+	IPv4Only
+	IPv6Only
+	PreferIPv4
+	PreferIPv6
+)
+
+const (
+	dialTimeout = 30 * time.Second
+	minDialTimeout = 2 * time.Second
+	dialKeepAlive = 30 * time.Second
+	dialFallbackDelay = 300 * time.Millisecond
+)
+
+// dialConfig of the Options, the pooled clients are keyed by it.
+type dialConfig struct {
+	hosts map[string]string // "host:port" or "host" => IP or host
+	resolver *net.Resolver
+	resolverKey string
+	ipPreference IPPreference
+	localAddr net.IP
+	dnsTTL time.Duration
+}
+
+func (dc *dialConfig) isDefault() bool {
+	return dc == nil || (len(dc.hosts) == 0 && dc.resolver == nil && dc.ipPreference == IPAny && dc.localAddr == nil && dc.dnsTTL <= 0)
+}
+
+// key of the pooled clients, "" for the default dialer
+func (dc *dialConfig) key() string {
+	if dc.isDefault() {
+		return ""
+	}
+	hosts := make([]string, 0, len(dc.hosts))
+	for h, ip := range dc.hosts {
+		hosts = append(hosts, h + "=" + ip)
+	}
+	sort.Strings(hosts)
+	return fmt.Sprintf("hosts=%s|resolver=%s|ip=%d|local=%s|ttl=%d", strings.Join(hosts, ","), dc.resolverKey, dc.ipPreference, dc.localAddr, dc.dnsTTL)
+}
+
+func (dc *dialConfig) dialContext() func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{KeepAlive: dialKeepAlive}
+	if dc.localAddr != nil {
+		dialer.LocalAddr = &net.TCPAddr{IP: dc.localAddr}
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		// the keys of hosts are in lower case
+		host = strings.ToLower(host)
+		if h, ok := dc.hosts[net.JoinHostPort(host, port)]; ok {
+			host = h
+		} else if h, ok = dc.hosts[host]; ok {
+			host = h
+		}
+
+		ips, err := dc.lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		if ips = dc.sortIPs(ips); len(ips) == 0 {
+			return nil, fmt.Errorf("no suitable address of %s", host)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, dialTimeout)
+		defer cancel()
+		return dialParallel(ctx, dialer, network, port, ips)
+	}
+}
+
+// the addresses of the other family are raced after dialFallbackDelay or the failure of the
+// first family (Happy Eyeballs, RFC 8305), as the default dialer of net does.
+func dialParallel(ctx context.Context, dialer *net.Dialer, network, port string, ips []net.IP) (net.Conn, error) {
+	var primaries, fallbacks []net.IP
+	isV4 := ips[0].To4() != nil
+	for _, ip := range ips {
+		if (ip.To4() != nil) == isV4 {
+			primaries = append(primaries, ip)
+		} else {
+			fallbacks = append(fallbacks, ip)
+		}
+	}
+	if len(fallbacks) == 0 {
+		return dialSerial(ctx, dialer, network, port, primaries)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type dialResult struct {
+		conn net.Conn
+		err error
+	}
+	results := make(chan dialResult, 2)
+	start := func(ips []net.IP) {
+		go func() {
+			conn, err := dialSerial(ctx, dialer, network, port, ips)
+			results <- dialResult{conn, err}
+		}()
+	}
+
+	start(primaries)
+	pending, fallbackStarted := 1, false
+	timer := time.NewTimer(dialFallbackDelay)
+	defer timer.Stop()
+	var firstErr error
+	for {
+		select {
+		case <-timer.C:
+			if !fallbackStarted {
+				fallbackStarted = true
+				pending++
+				start(fallbacks)
+			}
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				if pending > 0 {
+					// the other one is canceled, it is closed if connected anyway
+					go func() {
+						if r := <-results; r.conn != nil {
+							r.conn.Close()
+						}
+					}()
+				}
+				return r.conn, nil
+			}
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			if !fallbackStarted {
+				fallbackStarted = true
+				pending++
+				start(fallbacks)
+			} else if pending == 0 {
+				return nil, firstErr
+			}
+		}
+	}
+}
+
+// the addresses are dialed one by one, each of them has a part of the time left.
+func dialSerial(ctx context.Context, dialer *net.Dialer, network, port string, ips []net.IP) (conn net.Conn, err error) {
+	for i, ip := range ips {
+		dialCtx := ctx
+		if deadline, ok := ctx.Deadline(); ok {
+			timeout := time.Until(deadline) / time.Duration(len(ips) - i)
+			if timeout < minDialTimeout {
+				timeout = minDialTimeout
+			}
+			var cancel context.CancelFunc
+			dialCtx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		if conn, err = dialer.DialContext(dialCtx, network, net.JoinHostPort(ip.String(), port)); err == nil {
+			return conn, nil
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, err
+}
+
+func (dc *dialConfig) lookup(ctx context.Context, host string) ([]net.IP, error) {
+	if ip := net.ParseIP(strings.Trim(host, "[]")); ip != nil {
+		return []net.IP{ip}, nil
+	}
+	resolver := dc.resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	if dc.dnsTTL > 0 {
+		return sharedDNSCache.lookup(ctx, resolver, dc.resolverKey, host, dc.dnsTTL)
+	}
+	return lookupIP(ctx, resolver, host)
+}
+
+// the addresses of the preferred family come first, or only the addresses of the family.
+// the family of the local address is required if it is bound.
+func (dc *dialConfig) sortIPs(ips []net.IP) []net.IP {
+	var v4, v6 []net.IP
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			v4 = append(v4, ip)
+		} else {
+			v6 = append(v6, ip)
+		}
+	}
+	if dc.localAddr != nil {
+		if dc.localAddr.To4() != nil {
+			v6 = nil
+		} else {
+			v4 = nil
+		}
+	}
+
+	switch dc.ipPreference {
+	case IPv4Only:
+		return v4
+	case IPv6Only:
+		return v6
+	case PreferIPv4:
+		return append(v4, v6...)
+	case PreferIPv6:
+		return append(v6, v4...)
+	default:
+		if dc.localAddr != nil {
+			return append(v4, v6...)
+		}
+		return ips
+	}
+}
+
+func lookupIP(ctx context.Context, resolver *net.Resolver, host string) ([]net.IP, error) {
+	addrs, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, len(addrs))
+	for i, a := range addrs {
+		ips[i] = a.IP
+	}
+	return ips, nil
+}
+
+// resolver sending queries to the DNS server addr, whose port is 53 if not given.
+func newDNSResolver(addr string) *net.Resolver {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "53")
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			d := &net.Dialer{}
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+}
+
+// ---- DNS cache shared by the pooled transports ----
+type dnsCache struct {
+	mu sync.Mutex
+	entries map[string]*dnsEntry
+}
+
+type dnsEntry struct {
+	ips []net.IP
+	expires time.Time
+}
+
+var sharedDNSCache = &dnsCache{entries: map[string]*dnsEntry{}}
+
+func (c *dnsCache) lookup(ctx context.Context, resolver *net.Resolver, resolverKey, host string, ttl time.Duration) ([]net.IP, error) {
+	key := resolverKey + "|" + strings.ToLower(host)
+	now := time.Now()
+
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok && now.Before(e.expires) {
+		c.mu.Unlock()
+		return e.ips, nil
+	}
+	c.mu.Unlock()
+
+	ips, err := lookupIP(ctx, resolver, host)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, e := range c.entries {
+		// remove the expired ones
+		if now.After(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = &dnsEntry{ips: ips, expires: now.Add(ttl)}
+	return ips, nil
+}
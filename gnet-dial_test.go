@@ -0,0 +1,169 @@
+package gnet
+
+import (
+	"testing"
+	"net/http"
+	"net/http/httptest"
+	"crypto/tls"
+	"context"
+	"strings"
+	"time"
+	"fmt"
+	"net"
+	"io"
+)
+
+func TestResolveHost(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Host)
+	}))
+	defer ts.Close()
+	_, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+
+	if _, content, _, err := Http("http://api.gnet.test:" + port + "/", ResolveHost("api.gnet.test", "127.0.0.1")); err != nil || string(content) != "api.gnet.test:" + port {
+		t.Fatalf("unexpected %s, %v\n", content, err)
+	}
+	if _, content, _, err := Http("http://API.gnet.test:" + port + "/", ResolveHost("api.gnet.test", "127.0.0.1")); err != nil || string(content) != "API.gnet.test:" + port {
+		t.Fatalf("host override expected to be case-insensitive, got %s, %v\n", content, err)
+	}
+	dc := &getOptions(ResolveHost("api.gnet.test", "127.0.0.1")).dial
+	if conn, err := dc.dialContext()(context.Background(), "tcp", "API.GNET.test:" + port); err != nil {
+		t.Fatalf("host override expected to be case-insensitive, got %v\n", err)
+	} else {
+		conn.Close()
+	}
+	if _, content, _, err := Http("http://api.gnet.test:" + port + "/", ResolveHost("api.gnet.test:" + port, "localhost"), WithIPPreference(PreferIPv4)); err != nil || string(content) != "api.gnet.test:" + port {
+		t.Fatalf("unexpected %s, %v\n", content, err)
+	}
+	if _, _, _, err := Http("http://api.gnet.test:" + port + "/", ResolveHost("api.gnet.test", "127.0.0.1"), WithIPPreference(IPv6Only)); err == nil || !strings.Contains(err.Error(), "no suitable address") {
+		t.Fatalf("error expected with IPv6 only, got %v\n", err)
+	}
+	if _, content, _, err := Http(ts.URL, LocalAddr(net.ParseIP("127.0.0.1"))); err != nil || len(content) == 0 {
+		t.Fatalf("unexpected %s, %v\n", content, err)
+	}
+}
+
+func TestResolveHostTLS(t *testing.T) {
+	serverName := make(chan string, 1)
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Host)
+	}))
+	ts.TLS = &tls.Config{
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			serverName <- hello.ServerName
+			return nil, nil
+		},
+	}
+	ts.StartTLS()
+	defer ts.Close()
+	_, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+
+	if _, content, _, err := Http("https://secure.gnet.test:" + port + "/", ResolveHost("secure.gnet.test", "127.0.0.1")); err != nil || string(content) != "secure.gnet.test:" + port {
+		t.Fatalf("unexpected %s, %v\n", content, err)
+	}
+	if sni := <-serverName; sni != "secure.gnet.test" {
+		t.Fatalf("unexpected SNI %s\n", sni)
+	}
+}
+
+func TestDialFallback(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+
+	// the IPv6 address of the discard prefix never connects, IPv4 is raced after the fallback delay
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	defer cancel()
+	start := time.Now()
+	conn, err := dialParallel(ctx, &net.Dialer{}, "tcp", port, []net.IP{net.ParseIP("100::1"), net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	conn.Close()
+	if d := time.Since(start); d >= minDialTimeout {
+		t.Fatalf("fallback expected to be fast, took %v\n", d)
+	}
+}
+
+func TestDialConfigKey(t *testing.T) {
+	o1 := getOptions(ResolveHost("a.test", "127.0.0.1"))
+	o2 := getOptions(ResolveHost("a.test", "127.0.0.1"))
+	o3 := getOptions(ResolveHost("a.test", "127.0.0.2"))
+
+	if getHttpClient(0, &o1.dial) != getHttpClient(0, &o2.dial) {
+		t.Fatalf("same client expected for the same dial config\n")
+	}
+	if getHttpClient(0, &o1.dial) == getHttpClient(0, &o3.dial) {
+		t.Fatalf("different clients expected for different dial configs\n")
+	}
+	if getHttpsClient(0, &o1.dial) == getHttpsClient(0, &getOptions().dial) {
+		t.Fatalf("different clients expected for the default dial config\n")
+	}
+
+	o4 := cloneOptions(o1, ResolveHost("b.test", "127.0.0.1"))
+	if len(o1.dial.hosts) != 1 || len(o4.dial.hosts) != 2 {
+		t.Fatalf("hosts of the cloned options should be copied\n")
+	}
+}
+
+func TestClientPoolBounded(t *testing.T) {
+	first := getOptions(ResolveHost("first.test", "127.0.0.1"))
+	c := getHttpClient(0, &first.dial)
+	for i:=0; i<maxPooledClients; i++ {
+		o := getOptions(ResolveHost(fmt.Sprintf("host%d.test", i), "127.0.0.1"))
+		getHttpClient(0, &o.dial)
+	}
+	if getHttpClient(0, &first.dial) == c {
+		t.Fatalf("the least recently used client expected to be evicted\n")
+	}
+
+	p := newClientPool(2)
+	a, b := &http.Client{}, &http.Client{}
+	p.store("a", a)
+	p.store("b", b)
+	p.load("a")
+	p.store("c", &http.Client{})
+	if _, ok := p.load("b"); ok {
+		t.Fatalf("b should be evicted\n")
+	}
+	if c, ok := p.load("a"); !ok || c != a {
+		t.Fatalf("a expected in pool\n")
+	}
+}
+
+func TestDNSCache(t *testing.T) {
+	dc := &dialConfig{dnsTTL: time.Minute, resolverKey: "test"}
+	ips, err := dc.lookup(context.Background(), "localhost")
+	if err != nil {
+		t.Skipf("localhost not resolved: %v\n", err)
+	}
+	sharedDNSCache.mu.Lock()
+	e, ok := sharedDNSCache.entries["test|localhost"]
+	sharedDNSCache.mu.Unlock()
+	if !ok || len(e.ips) != len(ips) {
+		t.Fatalf("localhost expected in cache\n")
+	}
+
+	dc.ipPreference = IPv4Only
+	for _, ip := range dc.sortIPs([]net.IP{net.ParseIP("::1"), net.ParseIP("127.0.0.1")}) {
+		if ip.To4() == nil {
+			t.Fatalf("IPv4 only expected, got %s\n", ip)
+		}
+	}
+	dc.ipPreference = PreferIPv6
+	if ips := dc.sortIPs([]net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("::1")}); len(ips) != 2 || ips[0].To4() != nil {
+		t.Fatalf("IPv6 first expected, got %v\n", ips)
+	}
+}
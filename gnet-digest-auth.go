@@ -0,0 +1,223 @@
+package gnet
+
+import (
+	"crypto/sha256"
+	"crypto/rand"
+	"crypto/md5"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"hash"
+	"sync"
+	"fmt"
+	"io"
+)
+
+// challenges of servers are cached, so the nonce can be reused by the following calls
+// with increasing nonce count, and the 401 round trip can be saved.
+var digestSessions = &sync.Map{}
+
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	qop       string
+	mu sync.Mutex
+	nc uint32
+}
+
+type digestAuth struct {
+	user, passwd string
+	key string
+}
+
+func newDigestAuth(user, passwd string) *digestAuth {
+	return &digestAuth{user: user, passwd: passwd}
+}
+
+func (a *digestAuth) authorize(req *http.Request, body io.ReadSeeker) error {
+	a.key = fmt.Sprintf("%s@%s://%s", a.user, req.URL.Scheme, req.URL.Host)
+	c, ok := digestSessions.Load(a.key)
+	if !ok {
+		// the first request is sent without credentials to get the challenge
+		return nil
+	}
+	authorization, err := c.(*digestChallenge).authorization(a.user, a.passwd, req, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", authorization)
+	return nil
+}
+
+func (a *digestAuth) retry(resp *http.Response) bool {
+	c := parseDigestChallenge(resp.Header.Values("WWW-Authenticate"))
+	if c == nil {
+		return false
+	}
+	digestSessions.Store(a.key, c)
+	return true
+}
+
+func (c *digestChallenge) authorization(user, passwd string, req *http.Request, body io.ReadSeeker) (string, error) {
+	newHash := md5.New
+	algorithm := strings.ToUpper(c.algorithm)
+	switch strings.TrimSuffix(algorithm, "-SESS") {
+	case "", "MD5":
+	case "SHA-256":
+		newHash = sha256.New
+	default:
+		return "", fmt.Errorf("digest algorithm %s not supported", c.algorithm)
+	}
+	h := func(s string) string {
+		hh := newHash()
+		io.WriteString(hh, s)
+		return hex.EncodeToString(hh.Sum(nil))
+	}
+
+	c.mu.Lock()
+	c.nc += 1
+	nc := fmt.Sprintf("%08x", c.nc)
+	c.mu.Unlock()
+	cnonce := newCnonce()
+	uri := req.URL.RequestURI()
+
+	ha1 := h(fmt.Sprintf("%s:%s:%s", user, c.realm, passwd))
+	if strings.HasSuffix(algorithm, "-SESS") {
+		ha1 = h(fmt.Sprintf("%s:%s:%s", ha1, c.nonce, cnonce))
+	}
+
+	var ha2, response string
+	switch c.qop {
+	case "auth-int":
+		bodyHash, err := hashBody(newHash(), body)
+		if err != nil {
+			return "", err
+		}
+		ha2 = h(fmt.Sprintf("%s:%s:%s", req.Method, uri, bodyHash))
+	default:
+		ha2 = h(fmt.Sprintf("%s:%s", req.Method, uri))
+	}
+	if len(c.qop) == 0 {
+		response = h(fmt.Sprintf("%s:%s:%s", ha1, c.nonce, ha2))
+	} else {
+		response = h(fmt.Sprintf("%s:%s:%s:%s:%s:%s", ha1, c.nonce, nc, cnonce, c.qop, ha2))
+	}
+
+	b := &strings.Builder{}
+	fmt.Fprintf(b, `Digest username="%s", realm="%s", nonce="%s", uri="%s", response="%s"`,
+		escapeQuotes(user), escapeQuotes(c.realm), c.nonce, uri, response)
+	if len(c.algorithm) > 0 {
+		fmt.Fprintf(b, ", algorithm=%s", c.algorithm)
+	}
+	if len(c.opaque) > 0 {
+		fmt.Fprintf(b, `, opaque="%s"`, c.opaque)
+	}
+	if len(c.qop) > 0 {
+		fmt.Fprintf(b, `, qop=%s, nc=%s, cnonce="%s"`, c.qop, nc, cnonce)
+	}
+	return b.String(), nil
+}
+
+// hash of the entity body for qop=auth-int and signers, the body is rewound to be sent.
+// ErrBodyNotReplayable is returned for a body of Stream(), which can't be read twice.
+func hashBody(hh hash.Hash, body io.ReadSeeker) (string, error) {
+	if sb, ok := body.(*streamBody); ok && !sb.s.replayable {
+		return "", ErrBodyNotReplayable
+	}
+	if body != nil {
+		if _, err := io.Copy(hh, body); err != nil {
+			return "", err
+		}
+		if _, err := body.Seek(0, io.SeekStart); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(hh.Sum(nil)), nil
+}
+
+func newCnonce() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// find the best digest challenge in the values of WWW-Authenticate, SHA-256 is preferred to MD5.
+func parseDigestChallenge(values []string) *digestChallenge {
+	var best *digestChallenge
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if len(v) < 7 || !strings.EqualFold(v[:7], "Digest ") {
+			continue
+		}
+		params := parseAuthParams(v[7:])
+		c := &digestChallenge{
+			realm: params["realm"],
+			nonce: params["nonce"],
+			opaque: params["opaque"],
+			algorithm: params["algorithm"],
+		}
+		if len(c.nonce) == 0 {
+			continue
+		}
+		if qop, ok := params["qop"]; ok {
+			for _, q := range strings.Split(qop, ",") {
+				switch q = strings.TrimSpace(q); q {
+				case "auth":
+					c.qop = q
+				case "auth-int":
+					if len(c.qop) == 0 {
+						c.qop = q
+					}
+				}
+			}
+			if len(c.qop) == 0 {
+				continue
+			}
+		}
+		if best == nil || strings.HasPrefix(strings.ToUpper(c.algorithm), "SHA-256") {
+			best = c
+		}
+	}
+	return best
+}
+
+// parse key=value or key="quoted value" separated by commas
+func parseAuthParams(s string) map[string]string {
+	params := map[string]string{}
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			return params
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = strings.TrimLeft(s[eq+1:], " \t")
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			b := &strings.Builder{}
+			i := 1
+			for ; i<len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				b.WriteByte(s[i])
+			}
+			value = b.String()
+			if i < len(s) {
+				i++
+			}
+			s = s[i:]
+		} else {
+			end := strings.IndexByte(s, ',')
+			if end < 0 {
+				end = len(s)
+			}
+			value = strings.TrimSpace(s[:end])
+			s = s[end:]
+		}
+		params[key] = value
+	}
+}
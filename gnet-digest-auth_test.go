@@ -0,0 +1,72 @@
+package gnet
+
+import (
+	"testing"
+	"net/http"
+	"net/http/httptest"
+	"crypto/sha256"
+	"crypto/md5"
+	"encoding/hex"
+	"hash"
+	"fmt"
+	"io"
+)
+
+func digestServer(t *testing.T, algorithm, qop string, challenges *int) *httptest.Server {
+	newHash := md5.New
+	if algorithm == "SHA-256" {
+		newHash = sha256.New
+	}
+	h := func(s string) string {
+		hh := newHash()
+		io.WriteString(hh, s)
+		return hex.EncodeToString(hh.Sum(nil))
+	}
+	lastNc := ""
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		auth := r.Header.Get("Authorization")
+		if len(auth) < 7 {
+			*challenges += 1
+			w.Header().Add("WWW-Authenticate", fmt.Sprintf(`Digest realm="test", nonce="n1", opaque="o1", algorithm=%s, qop="%s"`, algorithm, qop))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		p := parseAuthParams(auth[7:])
+		ha2 := h(fmt.Sprintf("%s:%s", r.Method, p["uri"]))
+		if qop == "auth-int" {
+			var hh hash.Hash = newHash()
+			hh.Write(body)
+			ha2 = h(fmt.Sprintf("%s:%s:%x", r.Method, p["uri"], hh.Sum(nil)))
+		}
+		ha1 := h("user:test:pass")
+		expected := h(fmt.Sprintf("%s:%s:%s:%s:%s:%s", ha1, p["nonce"], p["nc"], p["cnonce"], p["qop"], ha2))
+		if p["response"] != expected || p["opaque"] != "o1" || p["nc"] <= lastNc {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		lastNc = p["nc"]
+		fmt.Fprintf(w, "%s", body)
+	}))
+}
+
+func TestDigestAuth(t *testing.T) {
+	for _, c := range [][2]string{{"MD5", "auth-int"}, {"SHA-256", "auth"}} {
+		challenges := 0
+		ts := digestServer(t, c[0], c[1], &challenges)
+		for i:=0; i<2; i++ {
+			status, content, _, err := Http(ts.URL + "/p?q=1", M(http.MethodPost), Params(map[string]string{"a": "b"}), DigestAuth("user", "pass"))
+			if err != nil {
+				t.Fatalf("%v\n", err)
+			}
+			if status != http.StatusOK || string(content) != "a=b" {
+				t.Fatalf("%s/%s: unexpected response %d %s\n", c[0], c[1], status, content)
+			}
+		}
+		if challenges != 1 {
+			t.Fatalf("the challenge is expected to be reused, but %d challenges\n", challenges)
+		}
+		ts.Close()
+	}
+}
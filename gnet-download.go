@@ -0,0 +1,313 @@
+package gnet
+
+import (
+	"crypto/sha256"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"hash"
+	"time"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	defaultDownloadRetries = 3
+	downloadRetryInterval = time.Second
+	partFileSuffix = ".part"
+	partMetaSuffix = ".part.meta"
+)
+
+// Download saves the content of url to path. The content is written to "path.part" first,
+// which is resumed with Range/If-Range after a failure, even by a later call, and renamed to path
+// after the checksum given by ExpectChecksum() or Digest/Content-MD5 headers is verified.
+// The timeout of WithTimeout() is applied to waiting for the response and every read of the body,
+// so a stalled server is retried. The options WithProgress(), ExpectChecksum() and MaxRetries() can be used.
+func Download(url, path string, options ...Option) error {
+	option := getOptions(options...)
+	retries := defaultDownloadRetries
+	if option.maxRetriesSet {
+		retries = option.maxRetries
+	}
+
+	d := &downloader{url: url, path: path, options: options, option: option}
+	var err error
+	for i:=0; i<=retries; i++ {
+		if i > 0 {
+			time.Sleep(downloadRetryInterval)
+		}
+		var retry bool
+		if retry, err = d.download(); err == nil || !retry {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return d.finish()
+}
+
+type downloader struct {
+	url, path string
+	options []Option
+	option *Options
+
+	validator string
+	total int64
+	digest http.Header // headers with digest of the full content
+}
+
+// returns whether it can be retried if error occurs
+func (d *downloader) download() (retry bool, err error) {
+	tmpFile := d.path + partFileSuffix
+	fp, err := os.OpenFile(tmpFile, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return false, err
+	}
+	defer fp.Close()
+
+	offset, _ := fp.Seek(0, io.SeekEnd)
+	if offset > 0 && len(d.validator) == 0 {
+		if d.validator = readPartMeta(d.path); len(d.validator) == 0 {
+			// it can't be resumed without knowing the content is not changed
+			offset = 0
+		}
+	}
+
+	options := append(d.options[:len(d.options):len(d.options)], DontReadRespBody())
+	if offset > 0 {
+		options = append(options, SetHeader("Range", fmt.Sprintf("bytes=%d-", offset)), SetHeader("If-Range", d.validator))
+	}
+	option := getOptions(options...)
+	option.streaming = true
+	option.idleTimeout = true
+	option.dontReadRespBody = true
+	option.method = http.MethodGet
+
+	status, _, resp, err := http_i(d.url, option)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	switch status {
+	case http.StatusOK:
+		offset = 0
+		d.total = resp.ContentLength
+		d.digest = resp.Header
+	case http.StatusPartialContent:
+		start, total, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			return true, fmt.Errorf("unexpected Content-Range %s", resp.Header.Get("Content-Range"))
+		}
+		d.total = total
+		if d.digest == nil && len(resp.Header.Get("Digest")) > 0 {
+			// instance digest of the full content
+			d.digest = http.Header{"Digest": resp.Header.Values("Digest")}
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		if _, total, ok := parseContentRange(resp.Header.Get("Content-Range")); ok && total == offset {
+			// it is done already
+			d.total = total
+			return false, nil
+		}
+		fp.Truncate(0)
+		removePartMeta(d.path)
+		return true, fmt.Errorf("range not satisfiable")
+	default:
+		return status >= http.StatusInternalServerError, fmt.Errorf("failed to download %s: status %d", d.url, status)
+	}
+
+	if v := rangeValidator(resp.Header); v != d.validator || offset == 0 {
+		d.validator = v
+		writePartMeta(d.path, v)
+	}
+	if err = fp.Truncate(offset); err != nil {
+		return false, err
+	}
+	if _, err = fp.Seek(offset, io.SeekStart); err != nil {
+		return false, err
+	}
+
+	var w io.Writer = fp
+	if d.option.progress != nil {
+		w = &progressWriter{w: fp, n: offset, total: d.total, progress: d.option.progress}
+		d.option.progress(offset, d.total)
+	}
+	n, err := io.Copy(w, resp.Body)
+	if err != nil {
+		return true, err
+	}
+	if d.total >= 0 && offset + n != d.total {
+		return true, fmt.Errorf("incomplete content: %d of %d bytes", offset + n, d.total)
+	}
+	return false, nil
+}
+
+// verify checksum and rename the temp file
+func (d *downloader) finish() error {
+	tmpFile := d.path + partFileSuffix
+	if err := verifyChecksum(tmpFile, d.option, d.digest); err != nil {
+		os.Remove(tmpFile)
+		removePartMeta(d.path)
+		return err
+	}
+	if err := os.Rename(tmpFile, d.path); err != nil {
+		return err
+	}
+	removePartMeta(d.path)
+	return nil
+}
+
+// the checksum of ExpectChecksum() is preferred to Digest/Content-MD5 headers.
+func verifyChecksum(file string, option *Options, header http.Header) error {
+	var h hash.Hash
+	var expected string
+	switch {
+	case len(option.checksum) > 0:
+		if h = newChecksumHash(option.checksumAlgorithm); h == nil {
+			return fmt.Errorf("checksum algorithm %s not supported", option.checksumAlgorithm)
+		}
+		expected = strings.ToLower(option.checksum)
+	default:
+		if h, expected = digestOfHeader(header); h == nil {
+			return nil
+		}
+	}
+
+	fp, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+	if _, err = io.Copy(h, fp); err != nil {
+		return err
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != expected {
+		return fmt.Errorf("checksum mismatch: expected %s, got %s", expected, sum)
+	}
+	return nil
+}
+
+func newChecksumHash(algorithm string) hash.Hash {
+	switch strings.ToLower(strings.ReplaceAll(algorithm, "-", "")) {
+	case "sha256":
+		return sha256.New()
+	case "md5":
+		return md5.New()
+	default:
+		return nil
+	}
+}
+
+// digest in hex from "Digest: SHA-256=<base64>" or "Content-MD5: <base64>"
+func digestOfHeader(header http.Header) (hash.Hash, string) {
+	if header == nil {
+		return nil, ""
+	}
+	var md5Sum string
+	for _, v := range header.Values("Digest") {
+		for _, d := range strings.Split(v, ",") {
+			d = strings.TrimSpace(d)
+			pos := strings.IndexByte(d, '=')
+			if pos < 0 {
+				continue
+			}
+			b, err := base64.StdEncoding.DecodeString(d[pos+1:])
+			if err != nil {
+				continue
+			}
+			switch strings.ToUpper(d[:pos]) {
+			case "SHA-256":
+				return sha256.New(), hex.EncodeToString(b)
+			case "MD5":
+				md5Sum = hex.EncodeToString(b)
+			}
+		}
+	}
+	if len(md5Sum) == 0 {
+		if v := header.Get("Content-MD5"); len(v) > 0 {
+			if b, err := base64.StdEncoding.DecodeString(v); err == nil {
+				md5Sum = hex.EncodeToString(b)
+			}
+		}
+	}
+	if len(md5Sum) > 0 {
+		return md5.New(), md5Sum
+	}
+	return nil, ""
+}
+
+// strong ETag or Last-Modified which can be used as If-Range
+func rangeValidator(header http.Header) string {
+	if etag := header.Get("ETag"); len(etag) > 0 && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return header.Get("Last-Modified")
+}
+
+// parse "bytes 100-199/1000" or "bytes */1000", total is -1 if it is "*"
+func parseContentRange(contentRange string) (start, total int64, ok bool) {
+	if !strings.HasPrefix(contentRange, "bytes ") {
+		return
+	}
+	r := strings.TrimPrefix(contentRange, "bytes ")
+	slash := strings.IndexByte(r, '/')
+	if slash < 0 {
+		return
+	}
+	var err error
+	if t := r[slash+1:]; t == "*" {
+		total = -1
+	} else if total, err = strconv.ParseInt(t, 10, 64); err != nil {
+		return
+	}
+	if r[:slash] == "*" {
+		return -1, total, true
+	}
+	dash := strings.IndexByte(r[:slash], '-')
+	if dash < 0 {
+		return
+	}
+	if start, err = strconv.ParseInt(r[:dash], 10, 64); err != nil {
+		return
+	}
+	return start, total, true
+}
+
+func readPartMeta(path string) string {
+	b, err := os.ReadFile(path + partMetaSuffix)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+func writePartMeta(path, validator string) {
+	if len(validator) == 0 {
+		removePartMeta(path)
+		return
+	}
+	os.WriteFile(path + partMetaSuffix, []byte(validator), 0644)
+}
+
+func removePartMeta(path string) {
+	os.Remove(path + partMetaSuffix)
+}
+
+type progressWriter struct {
+	w io.Writer
+	n, total int64
+	progress func(downloaded, total int64)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.n += int64(n)
+	p.progress(p.n, p.total)
+	return n, err
+}
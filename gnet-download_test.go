@@ -0,0 +1,147 @@
+package gnet
+
+import (
+	"testing"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"bytes"
+	"sync"
+	"time"
+	"os"
+)
+
+func TestDownload(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+
+	var mu sync.Mutex
+	var ranges []string
+	dropped := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		drop := !dropped
+		dropped = true
+		mu.Unlock()
+
+		w.Header().Set("ETag", `"v1"`)
+		if drop {
+			// send the half and drop the connection
+			w.Header().Set("Content-Length", "10000")
+			w.Write(content[:5000])
+			w.(http.Flusher).Flush()
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	}))
+	defer ts.Close()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file")
+
+	// the first call gives up after the connection is dropped, the next call resumes
+	if err := Download(ts.URL, path, MaxRetries(0)); err == nil {
+		t.Fatalf("error expected\n")
+	}
+	if _, err := os.Stat(path + partFileSuffix); err != nil {
+		t.Fatalf("part file expected: %v\n", err)
+	}
+
+	var downloaded, total int64
+	err := Download(ts.URL, path, ExpectChecksum("sha256", checksum), WithProgress(func(n, t int64) { downloaded, total = n, t }))
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if strings.Join(ranges, ",") != ",bytes=5000-" {
+		t.Fatalf("unexpected ranges %v\n", ranges)
+	}
+	if downloaded != 10000 || total != 10000 {
+		t.Fatalf("unexpected progress %d/%d\n", downloaded, total)
+	}
+	if b, _ := os.ReadFile(path); !bytes.Equal(b, content) {
+		t.Fatalf("content mismatch\n")
+	}
+	for _, f := range []string{path + partFileSuffix, path + partMetaSuffix} {
+		if _, err := os.Stat(f); !os.IsNotExist(err) {
+			t.Fatalf("%s expected to be removed\n", f)
+		}
+	}
+
+	// checksum mismatch
+	other := filepath.Join(dir, "other")
+	if err := Download(ts.URL, other, ExpectChecksum("sha256", strings.Repeat("0", 64))); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("checksum mismatch expected, got %v\n", err)
+	}
+	if _, err := os.Stat(other); !os.IsNotExist(err) {
+		t.Fatalf("%s expected not to be created\n", other)
+	}
+}
+
+func TestDownloadStalled(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	stalled := make(chan struct{})
+	var mu sync.Mutex
+	var ranges []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		n := len(ranges)
+		mu.Unlock()
+
+		w.Header().Set("ETag", `"v1"`)
+		switch n {
+		case 1:
+			// no response headers
+			<-stalled
+			return
+		case 2:
+			// the half is sent before the body stalls
+			w.Header().Set("Content-Length", "10000")
+			w.Write(content[:5000])
+			w.(http.Flusher).Flush()
+			<-stalled
+			return
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	}))
+	defer ts.Close()
+	defer close(stalled)
+
+	path := filepath.Join(t.TempDir(), "file")
+	if err := Download(ts.URL, path, WithTimeoutDuration(200*time.Millisecond), MaxRetries(2)); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if strings.Join(ranges, ",") != ",,bytes=5000-" {
+		t.Fatalf("unexpected ranges %v\n", ranges)
+	}
+	if b, _ := os.ReadFile(path); !bytes.Equal(b, content) {
+		t.Fatalf("content mismatch\n")
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	cases := []struct{
+		contentRange string
+		start, total int64
+		ok bool
+	}{
+		{"bytes 100-199/1000", 100, 1000, true},
+		{"bytes 0-9/*", 0, -1, true},
+		{"bytes */1000", -1, 1000, true},
+		{"items 0-9/10", 0, 0, false},
+		{"bytes 0-9", 0, 0, false},
+	}
+	for _, c := range cases {
+		start, total, ok := parseContentRange(c.contentRange)
+		if ok != c.ok || (ok && (start != c.start || total != c.total)) {
+			t.Fatalf("%s: unexpected %d, %d, %v\n", c.contentRange, start, total, ok)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package gnet
+
+import (
+	"encoding"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"fmt"
+)
+
+// how slices/arrays are encoded in form params
+type ArrayFormat int
+const (
+	ArrayRepeat   ArrayFormat = iota // a=1&a=2
+	ArrayBrackets                    // a[]=1&a[]=2
+	ArrayIndices                     // a[0]=1&a[1]=2
+	ArrayComma                       // a=1,2
+)
+
+const (
+	formTagName = "form"
+	defaultTimeLayout = time.RFC3339
+)
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+type formEncoder struct {
+	arrayFormat ArrayFormat
+	timeLayout  string
+}
+
+func (enc *formEncoder) layout() string {
+	if enc == nil || len(enc.timeLayout) == 0 {
+		return defaultTimeLayout
+	}
+	return enc.timeLayout
+}
+
+func (enc *formEncoder) format() ArrayFormat {
+	if enc == nil {
+		return ArrayRepeat
+	}
+	return enc.arrayFormat
+}
+
+// encode a map with any type of values or a struct with `form:"name,omitempty"` tags.
+func (enc *formEncoder) encode(params interface{}) (url.Values, error) {
+	u := url.Values{}
+	v := reflect.ValueOf(params)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return u, nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("only map with string keys can be used as http params")
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := enc.encodeValue(u, iter.Key().String(), iter.Value()); err != nil {
+				return nil, err
+			}
+		}
+		return u, nil
+	case reflect.Struct:
+		if v.Type() == timeType {
+			break
+		}
+		if err := enc.encodeStruct(u, "", v); err != nil {
+			return nil, err
+		}
+		return u, nil
+	}
+	return nil, fmt.Errorf("unknown type to build http params")
+}
+
+func (enc *formEncoder) encodeValue(u url.Values, key string, v reflect.Value) error {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			u.Add(key, "")
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	if s, ok, err := enc.scalar(v); ok || err != nil {
+		if err != nil {
+			return err
+		}
+		u.Add(key, s)
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		return enc.encodeSlice(u, key, v)
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			k, ok, err := enc.scalar(indirect(iter.Key()))
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("type %s of map key in %s can not be used as http params", iter.Key().Type(), key)
+			}
+			if err := enc.encodeValue(u, fmt.Sprintf("%s[%s]", key, k), iter.Value()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Struct:
+		return enc.encodeStruct(u, key, v)
+	default:
+		return fmt.Errorf("type %s of %s can not be used as http params", v.Type(), key)
+	}
+}
+
+func (enc *formEncoder) encodeSlice(u url.Values, key string, v reflect.Value) error {
+	format := enc.format()
+	n := v.Len()
+
+	if format == ArrayComma {
+		vals := make([]string, 0, n)
+		for i:=0; i<n; i++ {
+			s, ok, err := enc.scalar(indirect(v.Index(i)))
+			if err != nil {
+				return err
+			}
+			if !ok {
+				// elements are not scalar, comma-joining makes no sense
+				format = ArrayIndices
+				break
+			}
+			vals = append(vals, s)
+		}
+		if format == ArrayComma {
+			u.Add(key, strings.Join(vals, ","))
+			return nil
+		}
+	}
+
+	for i:=0; i<n; i++ {
+		var k string
+		switch format {
+		case ArrayBrackets:
+			k = fmt.Sprintf("%s[]", key)
+		case ArrayIndices:
+			k = fmt.Sprintf("%s[%d]", key, i)
+		default:
+			k = key
+		}
+		if err := enc.encodeValue(u, k, v.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (enc *formEncoder) encodeStruct(u url.Values, prefix string, v reflect.Value) error {
+	t := v.Type()
+	for i:=0; i<t.NumField(); i++ {
+		sf := t.Field(i)
+		if len(sf.PkgPath) > 0 && !sf.Anonymous {
+			// unexported
+			continue
+		}
+		name, omitEmpty := parseFormTag(sf)
+		if name == "-" {
+			continue
+		}
+		fv := v.Field(i)
+		if omitEmpty && fv.IsZero() {
+			continue
+		}
+
+		if sf.Anonymous && len(name) == 0 {
+			if ev := indirect(fv); ev.Kind() == reflect.Struct && ev.Type() != timeType {
+				// fields of embedded struct are promoted
+				if err := enc.encodeStruct(u, prefix, ev); err != nil {
+					return err
+				}
+				continue
+			}
+			if len(sf.PkgPath) > 0 {
+				continue
+			}
+		}
+
+		if len(name) == 0 {
+			name = sf.Name
+		}
+		if len(prefix) > 0 {
+			name = fmt.Sprintf("%s[%s]", prefix, name)
+		}
+		if err := enc.encodeValue(u, name, fv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// returns the string of a scalar value, ok is false if v is not a scalar.
+func (enc *formEncoder) scalar(v reflect.Value) (s string, ok bool, err error) {
+	if !v.IsValid() {
+		return "", true, nil
+	}
+	// values promoted from an unexported embedded struct can't be interfaced
+	if v.CanInterface() {
+		if v.Type() == timeType {
+			return v.Interface().(time.Time).Format(enc.layout()), true, nil
+		}
+		if v.Type().Implements(textMarshalerType) && (v.Kind() != reflect.Ptr || !v.IsNil()) {
+			b, e := v.Interface().(encoding.TextMarshaler).MarshalText()
+			return string(b), true, e
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), true, nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), true, nil
+	case reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 32), true, nil
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), true, nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes()), true, nil
+		}
+	}
+	return "", false, nil
+}
+
+func parseFormTag(sf reflect.StructField) (name string, omitEmpty bool) {
+	tag, ok := sf.Tag.Lookup(formTagName)
+	if !ok {
+		return "", false
+	}
+	parts := strings.Split(tag, ",")
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return parts[0], omitEmpty
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v
+}
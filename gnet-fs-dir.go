@@ -0,0 +1,308 @@
+package gnet
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"io/fs"
+	"regexp"
+	"strconv"
+	"strings"
+	"html"
+	"path"
+	"sort"
+	"sync/atomic"
+	"time"
+	"fmt"
+	"io"
+)
+
+var _ fs.ReadDirFS = (*FS)(nil)
+
+// ReadDir lists the directory name with WebDAV PROPFIND, or parses the index page of the
+// directory URL, which is nginx/Apache autoindex HTML or nginx autoindex_format json.
+// The entries are sorted by name.
+func (fsys *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	dirUrl := fsys.nameToUrl(name)
+	if !strings.HasSuffix(dirUrl, "/") {
+		dirUrl += "/"
+	}
+
+	var entries []fs.DirEntry
+	var err error
+	ok, webDAV := false, atomic.LoadInt32(&fsys.noWebDAV) == 0
+	if webDAV {
+		if entries, ok, err = fsys.propfindDir(name, dirUrl); err != nil {
+			return nil, err
+		}
+	}
+	if !ok {
+		if entries, err = fsys.indexDir(name, dirUrl); err != nil {
+			return nil, err
+		}
+		if webDAV {
+			// not a WebDAV server, PROPFIND is not tried any more
+			atomic.StoreInt32(&fsys.noWebDAV, 1)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+// ok is false if PROPFIND is denied or not supported, e.g. 404 or 403 of a static server,
+// or the server does not respond with multistatus, the index page is tried then.
+// The other errors, e.g. timeout or 5xx, are returned.
+func (fsys *FS) propfindDir(name, dirUrl string) (entries []fs.DirEntry, ok bool, err error) {
+	f, err := fsys.request("readdir", name, dirUrl, "PROPFIND", true,
+		Params(propfindBody), SetHeader("Depth", "1"), SetHeader(headerContentType, mimeXML),
+	)
+	if err != nil {
+		if isPropfindDenied(err) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	defer f.Close()
+	if f.Status != http.StatusMultiStatus {
+		return nil, false, nil
+	}
+
+	resources, e := parseMultistatus(f)
+	if e != nil {
+		return nil, false, &fs.PathError{Op: "readdir", Path: name, Err: e}
+	}
+	dirPath := path.Clean(f.Resp.Request.URL.Path)
+	entries = make([]fs.DirEntry, 0, len(resources))
+	for _, r := range resources {
+		p := path.Clean(r.Path)
+		if p == dirPath || path.Dir(p) != dirPath || r.Status != http.StatusOK {
+			// the directory itself, or the properties not found
+			continue
+		}
+		entries = append(entries, &dirEntry{
+			name: path.Base(p),
+			isDir: r.IsDir,
+			size: r.Size,
+			modTime: r.ModTime,
+		})
+	}
+	return entries, true, nil
+}
+
+// 403, 404, 405 or 501 to PROPFIND
+func isPropfindDenied(err error) bool {
+	pe, ok := err.(*fs.PathError)
+	return ok && (pe.Err == fs.ErrNotExist || pe.Err == fs.ErrPermission || isMethodNotAllowed(pe.Err))
+}
+
+// ok is false if the server does not respond with multistatus
+func (fsys *FS) propfindStat(name string) (fs.FileInfo, bool, error) {
+	if atomic.LoadInt32(&fsys.noWebDAV) != 0 {
+		return nil, false, nil
+	}
+	f, err := fsys.request("stat", name, fsys.nameToUrl(name), "PROPFIND", false,
+		Params(propfindBody), SetHeader("Depth", "0"), SetHeader(headerContentType, mimeXML),
+	)
+	if err != nil {
+		if pe := err.(*fs.PathError); pe.Err == fs.ErrNotExist || pe.Err == fs.ErrPermission {
+			return nil, false, err
+		}
+		return nil, false, nil
+	}
+	defer f.Close()
+	if f.Status != http.StatusMultiStatus {
+		return nil, false, nil
+	}
+
+	resources, err := parseMultistatus(f)
+	if err != nil {
+		return nil, false, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	for _, r := range resources {
+		if r.Status == http.StatusOK {
+			return &dirEntry{name: path.Base(name), isDir: r.IsDir, size: r.Size, modTime: r.ModTime}, true, nil
+		}
+	}
+	return nil, false, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+func (fsys *FS) indexDir(name, dirUrl string) ([]fs.DirEntry, error) {
+	f, err := fsys.request("readdir", name, dirUrl, http.MethodGet, false)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	body, err := io.ReadAll(f)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+
+	var entries []fs.DirEntry
+	switch ct := f.Resp.Header.Get(headerContentType); {
+	case strings.Contains(ct, "json"):
+		entries, err = parseJSONIndex(body)
+	case strings.Contains(ct, "html"):
+		entries = parseHTMLIndex(string(body))
+	default:
+		err = fmt.Errorf("unknown directory index of Content-Type %s", ct)
+	}
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	return entries, nil
+}
+
+// ---- nginx autoindex_format json ----
+
+type jsonIndexItem struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	MTime string `json:"mtime"`
+	Size  int64  `json:"size"`
+}
+
+func parseJSONIndex(body []byte) ([]fs.DirEntry, error) {
+	var items []jsonIndexItem
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil, err
+	}
+	entries := make([]fs.DirEntry, 0, len(items))
+	for _, item := range items {
+		modTime, _ := http.ParseTime(item.MTime)
+		entries = append(entries, &dirEntry{
+			name: item.Name,
+			isDir: item.Type == "directory",
+			size: item.Size,
+			modTime: modTime,
+		})
+	}
+	return entries, nil
+}
+
+// ---- nginx/Apache autoindex HTML ----
+
+var (
+	indexLinkRe = regexp.MustCompile(`(?is)<a\s[^>]*?href\s*=\s*"([^"]*)"[^>]*>.*?</a>`)
+	htmlTagRe = regexp.MustCompile(`(?s)<[^>]*>`)
+	indexTimeLayouts = []string{"02-Jan-2006 15:04", "2006-01-02 15:04", "02-Jan-2006 15:04:05", "2006-01-02 15:04:05"}
+)
+
+// every link to an entry of the directory is followed by the modification time and size
+//   nginx:  <a href="file.txt">file.txt</a>    02-Jan-2024 03:04    123
+//   Apache: <td><a href="file.txt">file.txt</a></td><td align="right">2024-01-02 03:04  </td><td align="right">1.2K</td>
+func parseHTMLIndex(body string) []fs.DirEntry {
+	links := indexLinkRe.FindAllStringSubmatchIndex(body, -1)
+	entries := make([]fs.DirEntry, 0, len(links))
+	seen := map[string]*dirEntry{}
+	for i, l := range links {
+		href := html.UnescapeString(body[l[2]:l[3]])
+		name, isDir, ok := indexEntryName(href)
+		if !ok {
+			continue
+		}
+		e, ok := seen[name]
+		if !ok {
+			// an entry may have more links, e.g. an icon and the name
+			e = &dirEntry{name: name, isDir: isDir}
+			seen[name] = e
+			entries = append(entries, e)
+		}
+
+		end := len(body)
+		if i+1 < len(links) {
+			end = links[i+1][0]
+		}
+		if modTime, size := parseIndexColumns(body[l[1]:end]); !modTime.IsZero() {
+			e.modTime, e.size = modTime, size
+		}
+	}
+	return entries
+}
+
+// only relative links to the entries of the directory are accepted
+func indexEntryName(href string) (name string, isDir bool, ok bool) {
+	if len(href) == 0 || strings.ContainsAny(href[:1], "?#/") || strings.Contains(href, "://") {
+		return
+	}
+	if pos := strings.IndexAny(href, "?#"); pos >= 0 {
+		href = href[:pos]
+	}
+	p, err := url.PathUnescape(strings.TrimPrefix(href, "./"))
+	if err != nil {
+		return
+	}
+	isDir = strings.HasSuffix(p, "/")
+	name = strings.TrimSuffix(p, "/")
+	if len(name) == 0 || name == "." || name == ".." || strings.Contains(name, "/") {
+		return
+	}
+	return name, isDir, true
+}
+
+func parseIndexColumns(columns string) (modTime time.Time, size int64) {
+	fields := strings.Fields(html.UnescapeString(htmlTagRe.ReplaceAllString(columns, " ")))
+	if len(fields) < 2 {
+		return
+	}
+	for _, layout := range indexTimeLayouts {
+		if t, err := time.Parse(layout, fields[0] + " " + fields[1]); err == nil {
+			modTime = t
+			break
+		}
+	}
+	if len(fields) > 2 {
+		size = parseIndexSize(fields[2])
+	}
+	return
+}
+
+// "123", "1.2K", "3M" or "-"
+func parseIndexSize(s string) int64 {
+	unit := int64(1)
+	switch s[len(s)-1] {
+	case 'K', 'k':
+		unit = 1 << 10
+	case 'M':
+		unit = 1 << 20
+	case 'G':
+		unit = 1 << 30
+	case 'T':
+		unit = 1 << 40
+	}
+	if unit > 1 {
+		s = s[:len(s)-1]
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return int64(f * float64(unit))
+}
+
+// ---- implementation of fs.DirEntry and fs.FileInfo of the entries ----
+type dirEntry struct {
+	name string
+	isDir bool
+	size int64
+	modTime time.Time
+}
+
+func (e *dirEntry) Name() string { return e.name }
+func (e *dirEntry) IsDir() bool { return e.isDir }
+func (e *dirEntry) Type() fs.FileMode { return e.Mode().Type() }
+func (e *dirEntry) Info() (fs.FileInfo, error) { return e, nil }
+func (e *dirEntry) Size() int64 { return e.size }
+func (e *dirEntry) ModTime() time.Time { return e.modTime }
+func (e *dirEntry) Sys() interface{} { return nil }
+
+func (e *dirEntry) Mode() fs.FileMode {
+	if e.isDir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
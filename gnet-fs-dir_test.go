@@ -0,0 +1,174 @@
+package gnet
+
+import (
+	"testing"
+	"net/http"
+	"net/http/httptest"
+	"golang.org/x/net/webdav"
+	"encoding/json"
+	"io/fs"
+	"strconv"
+	"strings"
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+	"os"
+)
+
+const nginxIndex = `<html>
+<head><title>Index of /dir/</title></head>
+<body>
+<h1>Index of /dir/</h1><hr><pre><a href="../">../</a>
+<a href="sub/">sub/</a>                                               02-Jan-2024 03:04                   -
+<a href="a%20b.txt">a b.txt</a>                                            02-Jan-2024 03:05                 123
+</pre><hr></body>
+</html>`
+
+const apacheIndex = `<table>
+<tr><th valign="top"><img src="/icons/blank.gif" alt="[ICO]"></th><th><a href="?C=N;O=D">Name</a></th><th><a href="?C=M;O=A">Last modified</a></th><th><a href="?C=S;O=A">Size</a></th></tr>
+<tr><td valign="top"><img src="/icons/back.gif" alt="[PARENTDIR]"></td><td><a href="/">Parent Directory</a></td><td>&nbsp;</td><td align="right">  - </td></tr>
+<tr><td valign="top"><a href="file.txt"><img src="/icons/text.gif" alt="[TXT]"></a></td><td><a href="file.txt">file.txt</a></td><td align="right">2024-01-02 03:04  </td><td align="right">1.5K</td><td>&nbsp;</td></tr>
+<tr><td valign="top"><img src="/icons/folder.gif" alt="[DIR]"></td><td><a href="sub/">sub/</a></td><td align="right">2024-01-02 03:05  </td><td align="right">  - </td><td>&nbsp;</td></tr>
+</table>`
+
+func TestParseHTMLIndex(t *testing.T) {
+	cases := []struct{
+		body string
+		expected string
+	}{
+		{nginxIndex, "sub/:0:2024-01-02 03:04,a b.txt:123:2024-01-02 03:05"},
+		{apacheIndex, "file.txt:1536:2024-01-02 03:04,sub/:0:2024-01-02 03:05"},
+	}
+	for _, c := range cases {
+		var got []string
+		for _, e := range parseHTMLIndex(c.body) {
+			fi, _ := e.Info()
+			name := e.Name()
+			if e.IsDir() {
+				name += "/"
+			}
+			got = append(got, name + ":" + strconv.FormatInt(fi.Size(), 10) + ":" + fi.ModTime().Format("2006-01-02 15:04"))
+		}
+		if strings.Join(got, ",") != c.expected {
+			t.Fatalf("expected %s, got %s\n", c.expected, strings.Join(got, ","))
+		}
+	}
+}
+
+func TestWalkDir(t *testing.T) {
+	// nginx autoindex_format json
+	tree := map[string][]jsonIndexItem{
+		"/": {{Name: "sub", Type: "directory", MTime: "Tue, 02 Jan 2024 03:04:05 GMT"}, {Name: "a.txt", Type: "file", Size: 1}},
+		"/sub/": {{Name: "b.txt", Type: "file", Size: 2}},
+	}
+	jsonServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		items, ok := tree[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(items)
+	}))
+	defer jsonServer.Close()
+
+	// WebDAV
+	memFS := webdav.NewMemFS()
+	ctx := context.Background()
+	memFS.Mkdir(ctx, "/sub", 0755)
+	for name, content := range map[string]string{"/a.txt": "a", "/sub/b.txt": "bb"} {
+		f, _ := memFS.OpenFile(ctx, name, os.O_CREATE|os.O_WRONLY, 0644)
+		f.Write([]byte(content))
+		f.Close()
+	}
+	davServer := httptest.NewServer(&webdav.Handler{FileSystem: memFS, LockSystem: webdav.NewMemLS()})
+	defer davServer.Close()
+
+	for _, ts := range []*httptest.Server{jsonServer, davServer} {
+		var walked []string
+		err := fs.WalkDir(NewFS(ts.URL), ".", func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			fi, _ := d.Info()
+			if !d.IsDir() {
+				walked = append(walked, p + ":" + strconv.FormatInt(fi.Size(), 10))
+			} else {
+				walked = append(walked, p + "/")
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%v\n", err)
+		}
+		if expected := "./,a.txt:1,sub/,sub/b.txt:2"; strings.Join(walked, ",") != expected {
+			t.Fatalf("expected %s, got %s\n", expected, strings.Join(walked, ","))
+		}
+	}
+
+	entries, err := fs.ReadDir(NewFS(jsonServer.URL), ".")
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("unexpected %v, %v\n", entries, err)
+	}
+	if fi, _ := entries[1].Info(); !fi.ModTime().Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Fatalf("unexpected mtime %v\n", fi.ModTime())
+	}
+}
+
+func TestReadDirPropfindDenied(t *testing.T) {
+	// a static server denying PROPFIND, like Apache without WebDAV
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method != http.MethodGet:
+			w.WriteHeader(http.StatusForbidden)
+		case r.URL.Path == "/dir/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(nginxIndex))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	fsys := NewFS(ts.URL)
+	if _, err := fsys.ReadDir("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("ErrNotExist expected, got %v\n", err)
+	}
+	entries, err := fsys.ReadDir("dir")
+	if err != nil || len(entries) != 2 || entries[0].Name() != "a b.txt" || !entries[1].IsDir() {
+		t.Fatalf("unexpected %v, %v\n", entries, err)
+	}
+}
+
+func TestReadDirPropfindFailure(t *testing.T) {
+	memFS := webdav.NewMemFS()
+	memFS.Mkdir(context.Background(), "/dir", 0755)
+	dav := &webdav.Handler{FileSystem: memFS, LockSystem: webdav.NewMemLS()}
+	var propfinds int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PROPFIND" && atomic.AddInt32(&propfinds, 1) == 1 {
+			// transient failure
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		dav.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	fsys := NewFS(ts.URL)
+	if _, err := fsys.ReadDir("dir"); err == nil {
+		t.Fatalf("error expected\n")
+	}
+	// PROPFIND is still used
+	if _, err := fsys.ReadDir("dir"); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if n := atomic.LoadInt32(&propfinds); n != 2 {
+		t.Fatalf("2 PROPFIND expected, got %d\n", n)
+	}
+}
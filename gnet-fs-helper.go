@@ -1,5 +1,3 @@
-// remove go1.16 dependency, build go1.16
-
 package gnet
 
 import (
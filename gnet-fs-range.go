@@ -0,0 +1,285 @@
+package gnet
+
+import (
+	"net/http"
+	"sync"
+	"fmt"
+	"io"
+)
+
+const (
+	rangeBlockSize = 64 * 1024
+	rangeCacheBlocks = 16
+	rangeReadAheadBlocks = 4
+)
+
+// ---- implementation of io.ReaderAt and io.Seeker with Range requests ----
+
+// ReadAt reads len(p) bytes at offset off of the remote file with Range requests.
+// Blocks are cached and read ahead, so small reads near each other are served by one request.
+// It is safe to be called concurrently.
+func (f *File) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("negative offset")
+	}
+	ra := f.rangeReader()
+	size, err := ra.getSize()
+	if err != nil {
+		return 0, err
+	}
+	if off >= size {
+		return 0, io.EOF
+	}
+
+	n := 0
+	for n < len(p) && off < size {
+		idx := off / rangeBlockSize
+		b, err := ra.block(idx)
+		if err != nil {
+			return n, err
+		}
+		pos := off - idx * rangeBlockSize
+		if pos >= int64(len(b)) {
+			return n, io.ErrUnexpectedEOF
+		}
+		c := copy(p[n:], b[pos:])
+		n += c
+		off += int64(c)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Seek sets the offset for the next Read. Read is served by ReadAt() after the offset is changed,
+// the response body of the sequential Read is closed then.
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = f.pos + offset
+	case io.SeekEnd:
+		size, err := f.Size()
+		if err != nil {
+			return 0, err
+		}
+		abs = size + offset
+	default:
+		return 0, fmt.Errorf("invalid whence %d", whence)
+	}
+	if abs < 0 {
+		return 0, fmt.Errorf("negative position")
+	}
+	if abs != f.pos && !f.seeked {
+		f.seeked = true
+		if f.Status > 0 && f.Err == nil && f.Resp.Body != nil {
+			f.Resp.Body.Close()
+		}
+	}
+	f.pos = abs
+	return abs, nil
+}
+
+// Size of the remote file, it is got from Content-Range of a Range request.
+func (f *File) Size() (int64, error) {
+	return f.rangeReader().getSize()
+}
+
+func (f *File) rangeReader() *rangeReader {
+	f.raOnce.Do(func() {
+		f.ra = &rangeReader{url: f.url, option: f.option, size: -1}
+	})
+	return f.ra
+}
+
+type rangeReader struct {
+	url string
+	option *Options
+
+	mu sync.Mutex
+	size int64
+	validator string
+	blocks map[int64][]byte
+	lru []int64 // index of blocks, the most recently used is the last
+	inflight map[int64]*rangeFetch // blocks being fetched
+}
+
+// a request fetching blocks from idx, the readers of these blocks wait for done.
+type rangeFetch struct {
+	idx int64
+	blocks [][]byte
+	err error
+	done chan struct{}
+}
+
+func (ra *rangeReader) getSize() (int64, error) {
+	ra.mu.Lock()
+	size := ra.size
+	ra.mu.Unlock()
+	if size >= 0 {
+		return size, nil
+	}
+	// the first block is read ahead
+	if _, err := ra.block(0); err != nil {
+		return 0, err
+	}
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+	return ra.size, nil
+}
+
+// the lock is not held while fetching, so blocks can be fetched concurrently.
+// a block being fetched is not requested again, the fetch is waited for instead.
+func (ra *rangeReader) block(idx int64) ([]byte, error) {
+	ra.mu.Lock()
+	if b, ok := ra.blocks[idx]; ok {
+		ra.touch(idx)
+		ra.mu.Unlock()
+		return b, nil
+	}
+	if f, ok := ra.inflight[idx]; ok {
+		ra.mu.Unlock()
+		<-f.done
+		return f.block(idx)
+	}
+	f := ra.startFetch(idx)
+	ra.mu.Unlock()
+
+	ra.fetchBlocks(f)
+	return f.block(idx)
+}
+
+func (f *rangeFetch) block(idx int64) ([]byte, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if i := idx - f.idx; i < int64(len(f.blocks)) {
+		return f.blocks[i], nil
+	}
+	return nil, nil
+}
+
+// blocks from idx to the next cached or fetched one are fetched in a request, ra.mu is held.
+func (ra *rangeReader) startFetch(idx int64) *rangeFetch {
+	count := int64(1)
+	for ; count < rangeReadAheadBlocks; count++ {
+		if _, ok := ra.blocks[idx+count]; ok {
+			break
+		}
+		if _, ok := ra.inflight[idx+count]; ok {
+			break
+		}
+		if ra.size >= 0 && (idx+count) * rangeBlockSize >= ra.size {
+			break
+		}
+	}
+	f := &rangeFetch{idx: idx, blocks: make([][]byte, 0, count), done: make(chan struct{})}
+	if ra.inflight == nil {
+		ra.inflight = map[int64]*rangeFetch{}
+	}
+	for i:=int64(0); i<count; i++ {
+		ra.inflight[idx+i] = f
+	}
+	return f
+}
+
+func (ra *rangeReader) fetchBlocks(f *rangeFetch) {
+	ra.mu.Lock()
+	count := int64(cap(f.blocks))
+	start, end := f.idx * rangeBlockSize, (f.idx + count) * rangeBlockSize - 1
+	if ra.size >= 0 && end >= ra.size {
+		end = ra.size - 1
+	}
+	validator := ra.validator
+	ra.mu.Unlock()
+
+	content, total, v, err := ra.fetchRange(start, end, validator)
+
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+	if err == nil {
+		ra.size = total
+		if len(ra.validator) == 0 {
+			ra.validator = v
+		}
+		for i:=int64(0); i<count && len(content) > 0; i++ {
+			b := content
+			if len(b) > rangeBlockSize {
+				b = b[:rangeBlockSize]
+			}
+			content = content[len(b):]
+			ra.put(f.idx+i, b)
+			f.blocks = append(f.blocks, b)
+		}
+	}
+	f.err = err
+	for i:=int64(0); i<count; i++ {
+		delete(ra.inflight, f.idx+i)
+	}
+	close(f.done)
+}
+
+// content of range start-end and the total size, validator is sent as If-Range.
+func (ra *rangeReader) fetchRange(start, end int64, validator string) (content []byte, total int64, newValidator string, err error) {
+	options := []Option{SetHeader("Range", fmt.Sprintf("bytes=%d-%d", start, end))}
+	if len(validator) > 0 {
+		options = append(options, SetHeader("If-Range", validator))
+	}
+	option := cloneOptions(ra.option, options...)
+	option.method = http.MethodGet
+	option.params = nil
+	option.jsonCall = false
+	option.dontReadRespBody = false
+
+	status, content, resp, err := http_i(ra.url, option)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	switch status {
+	case http.StatusPartialContent:
+		s, total, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || s != start || total < 0 {
+			return nil, 0, "", fmt.Errorf("unexpected Content-Range %s", resp.Header.Get("Content-Range"))
+		}
+		return content, total, rangeValidator(resp.Header), nil
+	case http.StatusRequestedRangeNotSatisfiable:
+		_, total, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || total < 0 {
+			return nil, 0, "", fmt.Errorf("range %d-%d not satisfiable", start, end)
+		}
+		return nil, total, validator, nil
+	case http.StatusOK:
+		if len(validator) > 0 {
+			return nil, 0, "", fmt.Errorf("%s is changed", ra.url)
+		}
+		return nil, 0, "", fmt.Errorf("range requests not supported by %s", ra.url)
+	default:
+		return nil, 0, "", fmt.Errorf("failed to read range %d-%d of %s: status %d", start, end, ra.url, status)
+	}
+}
+
+func (ra *rangeReader) put(idx int64, b []byte) {
+	if ra.blocks == nil {
+		ra.blocks = make(map[int64][]byte, rangeCacheBlocks)
+	}
+	if _, ok := ra.blocks[idx]; !ok && len(ra.blocks) >= rangeCacheBlocks {
+		// evict the least recently used
+		delete(ra.blocks, ra.lru[0])
+		ra.lru = ra.lru[1:]
+	}
+	ra.blocks[idx] = b
+	ra.touch(idx)
+}
+
+func (ra *rangeReader) touch(idx int64) {
+	for i, v := range ra.lru {
+		if v == idx {
+			ra.lru = append(ra.lru[:i], ra.lru[i+1:]...)
+			break
+		}
+	}
+	ra.lru = append(ra.lru, idx)
+}
@@ -0,0 +1,133 @@
+package gnet
+
+import (
+	"testing"
+	"net/http"
+	"net/http/httptest"
+	"archive/zip"
+	"strings"
+	"bytes"
+	"sync"
+	"sync/atomic"
+	"time"
+	"io"
+)
+
+func TestFileReadAt(t *testing.T) {
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	big, _ := zw.CreateHeader(&zip.FileHeader{Name: "big.bin", Method: zip.Store})
+	big.Write(bytes.Repeat([]byte("x"), 2 * 1024 * 1024))
+	small, _ := zw.Create("small.txt")
+	io.WriteString(small, "hello range")
+	zw.Close()
+	content := buf.Bytes()
+
+	var requests, sent int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		if !strings.HasPrefix(r.Header.Get("Range"), "bytes=") {
+			t.Errorf("Range expected\n")
+		}
+		w.Header().Set("ETag", `"v1"`)
+		cw := &countingWriter{ResponseWriter: w, n: &sent}
+		http.ServeContent(cw, r, "", time.Time{}, bytes.NewReader(content))
+	}))
+	defer ts.Close()
+
+	f := Get(ts.URL)
+	defer f.Close()
+	size, err := f.Size()
+	if err != nil || size != int64(len(content)) {
+		t.Fatalf("unexpected size %d, %v\n", size, err)
+	}
+	zr, err := zip.NewReader(f, size)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	for _, zf := range zr.File {
+		if zf.Name != "small.txt" {
+			continue
+		}
+		rc, _ := zf.Open()
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		if string(b) != "hello range" {
+			t.Fatalf("unexpected content %s\n", b)
+		}
+	}
+	if sent >= int64(len(content)) / 4 || requests > 4 {
+		t.Fatalf("too many bytes sent: %d of %d in %d requests\n", sent, len(content), requests)
+	}
+
+	// Seek and Read
+	if _, err = f.Seek(-int64(len(content)) + 1, io.SeekEnd); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	b := make([]byte, 10)
+	if n, err := io.ReadFull(f, b); err != nil || !bytes.Equal(b[:n], content[1:11]) {
+		t.Fatalf("unexpected %q, %v\n", b[:n], err)
+	}
+	if pos, _ := f.Seek(0, io.SeekCurrent); pos != 11 {
+		t.Fatalf("unexpected position %d\n", pos)
+	}
+}
+
+func TestFileReadAtConcurrent(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 64 * 1024)
+	var requests, inflight int64
+	both := make(chan struct{})
+	var bothOnce sync.Once
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt64(&requests, 1) > 1 {
+			// the requests of 2 different blocks must be in flight at the same time
+			if atomic.AddInt64(&inflight, 1) == 2 {
+				bothOnce.Do(func() { close(both) })
+			}
+			select {
+			case <-both:
+			case <-time.After(5 * time.Second):
+			}
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	}))
+	defer ts.Close()
+
+	f := Get(ts.URL)
+	defer f.Close()
+	if _, err := f.Size(); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	// 2 readers of each of 2 far blocks, the same block is fetched only once
+	offsets := []int64{rangeBlockSize * 8, rangeBlockSize * 8 + 10, rangeBlockSize * 12, rangeBlockSize * 12 + 10}
+	var wg sync.WaitGroup
+	start := time.Now()
+	for _, off := range offsets {
+		wg.Add(1)
+		go func(off int64) {
+			defer wg.Done()
+			b := make([]byte, 16)
+			if _, err := f.ReadAt(b, off); err != nil || !bytes.Equal(b, content[off:off+16]) {
+				t.Errorf("unexpected %q at %d, %v\n", b, off, err)
+			}
+		}(off)
+	}
+	wg.Wait()
+	if time.Since(start) >= 5 * time.Second {
+		t.Fatalf("blocks not fetched concurrently\n")
+	}
+	if n := atomic.LoadInt64(&requests); n != 3 {
+		t.Fatalf("3 requests expected, got %d\n", n)
+	}
+}
+
+type countingWriter struct {
+	http.ResponseWriter
+	n *int64
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	atomic.AddInt64(w.n, int64(len(b)))
+	return w.ResponseWriter.Write(b)
+}
@@ -1,12 +1,13 @@
-// remove go1.16 dependency build go1.16
-
 package gnet
 
 import (
-	// "io/fs"
-	"os"
+	"io/fs"
+	"sync"
 	"time"
 	"path"
+	"strings"
+	"fmt"
+	"io"
 	"net/url"
 	"net/http"
 )
@@ -18,37 +19,55 @@ type Result struct {
 	Err error
 }
 
-func HttpRequest(url string, options ...Option) *File /*fs.File*/ {
+func HttpRequest(url string, options ...Option) *File {
 	return gnet_fs(url, "", options...)
 }
 
-func Get(url string, options ...Option) *File /*fs.File*/ {
+func Get(url string, options ...Option) *File {
 	return gnet_fs(url, http.MethodGet, options...)
 }
 
-func Post(url string, options ...Option) *File /*fs.File*/ {
+func Post(url string, options ...Option) *File {
 	return gnet_fs(url, http.MethodPost, options...)
 }
 
-func Put(url string, options ...Option) *File /*fs.File*/ {
+func Put(url string, options ...Option) *File {
 	return gnet_fs(url, http.MethodPut, options...)
 }
 
-func Delete(url string, options ...Option) *File /*fs.File*/ {
-	return gnet_fs(url, http.MethodPut, options...)
+func Delete(url string, options ...Option) *File {
+	return gnet_fs(url, http.MethodDelete, options...)
 }
 
-func Head(url string, options ...Option) *File /*fs.File*/ {
+func Head(url string, options ...Option) *File {
 	return gnet_fs(url, http.MethodHead, options...)
 }
 
-func gnet_fs(url string, method string, options ...Option) *File /*fs.File*/ {
+func Patch(url string, options ...Option) *File {
+	return gnet_fs(url, http.MethodPatch, options...)
+}
+
+// OPTIONS request, e.g. a preflight probe
+func HttpOptions(url string, options ...Option) *File {
+	return gnet_fs(url, http.MethodOptions, options...)
+}
+
+func Trace(url string, options ...Option) *File {
+	return gnet_fs(url, http.MethodTrace, options...)
+}
+
+// request with any method, e.g. PROPFIND, MKCOL
+func CustomMethod(method, url string, options ...Option) *File {
+	return gnet_fs(url, method, options...)
+}
+
+func gnet_fs(url string, method string, options ...Option) *File {
 	option := getOptions(options...)
 	option.dontReadRespBody = true
 	return gnet_fs_i(url, method, option)
 }
 
-func gnet_fs_i(url, method string, option *Options) *File /*fs.File*/ {
+func gnet_fs_i(url, method string, option *Options) *File {
 	option.method = method
 	return &File{
 		url: url,
@@ -56,27 +75,154 @@ func gnet_fs_i(url, method string, option *Options) *File /*fs.File*/ {
 	}
 }
 
-/*
 // ---- implementation of fs.FS ----
-type gufs_t struct {
+
+// FS is a read-only fs.FS rooted at a base URL, names are mapped to the paths relative to it.
+// 404 is reported as fs.ErrNotExist, and 401/403 as fs.ErrPermission.
+type FS struct {
+	baseUrl string
+	multiBase *BaseUrl
+	options []Option
+	noWebDAV int32 // PROPFIND is not supported
 }
 
 var (
-	gufs = &gufs_t{}
+	_ fs.FS = (*FS)(nil)
+	_ fs.StatFS = (*FS)(nil)
+	_ fs.ReadFileFS = (*FS)(nil)
 )
 
-func (gufs *gufs_t) Open(name string) (fs.File, error) {
-	return Get(name)
-}*/
+// fs.FS rooted at baseUrl, options are applied to every request.
+func NewFS(baseUrl string, options ...Option) *FS {
+	return &FS{baseUrl: strings.TrimSuffix(baseUrl, "/"), options: options}
+}
+
+// fs.FS rooted at the base URLs of b, the next base URL is tried if one fails.
+func (b *BaseUrl) FS(options ...Option) *FS {
+	return &FS{multiBase: b, options: options}
+}
+
+func (fsys *FS) Open(name string) (fs.File, error) {
+	return fsys.open("open", name, http.MethodGet, true)
+}
+
+func (fsys *FS) Stat(name string) (fs.FileInfo, error) {
+	f, err := fsys.open("stat", name, http.MethodHead, false)
+	if err != nil {
+		if pe, ok := err.(*fs.PathError); !ok || !isMethodNotAllowed(pe.Err) {
+			return nil, err
+		}
+		// HEAD not supported, e.g. a collection of WebDAV
+		if fi, ok, e := fsys.propfindStat(name); e != nil || ok {
+			return fi, e
+		}
+		if f, err = fsys.open("stat", name, http.MethodGet, true); err != nil {
+			return nil, err
+		}
+	}
+	f.Close()
+	return &FileInfo{f: f}, nil
+}
+
+func (fsys *FS) ReadFile(name string) ([]byte, error) {
+	f, err := fsys.open("read", name, http.MethodGet, false)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	return b, nil
+}
+
+func (fsys *FS) open(op, name, method string, streaming bool) (*File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return fsys.request(op, name, fsys.nameToUrl(name), method, streaming)
+}
+
+// the response is checked with statusError(), the body must be closed if no error.
+// options are applied after the params of FS options are cleared.
+func (fsys *FS) request(op, name, url, method string, streaming bool, options ...Option) (*File, error) {
+	option := getOptions(fsys.options...)
+	if fsys.multiBase != nil {
+		option.multiBase = fsys.multiBase
+	}
+	option.jsonCall = false
+	option.params = nil
+	option = cloneOptions(option, options...)
+	option.dontReadRespBody = true
+	option.streaming = streaming
+
+	f := gnet_fs_i(url, method, option)
+	f.name = name
+	f.run()
+	err := f.Err
+	if err == nil {
+		err = statusError(f.Status)
+	}
+	if err != nil {
+		if f.Resp != nil && f.Resp.Body != nil {
+			f.Resp.Body.Close()
+		}
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	return f, nil
+}
+
+// the path segments are escaped, "." is the root
+func (fsys *FS) nameToUrl(name string) string {
+	if name == "." {
+		return fsys.baseUrl + "/"
+	}
+	segs := strings.Split(name, "/")
+	for i, seg := range segs {
+		segs[i] = url.PathEscape(seg)
+	}
+	return fsys.baseUrl + "/" + strings.Join(segs, "/")
+}
+
+type statusErr int
+
+func (e statusErr) Error() string {
+	return fmt.Sprintf("status %d", int(e))
+}
+
+func statusError(status int) error {
+	switch {
+	case status < http.StatusMultipleChoices:
+		return nil
+	case status == http.StatusNotFound || status == http.StatusGone:
+		return fs.ErrNotExist
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return fs.ErrPermission
+	default:
+		return statusErr(status)
+	}
+}
+
+func isMethodNotAllowed(err error) bool {
+	e, ok := err.(statusErr)
+	return ok && (e == http.StatusMethodNotAllowed || e == http.StatusNotImplemented)
+}
 
 // ---- implementation of fs.File ----
 type File struct {
 	url string
+	name string // name in FS
 	option *Options
 	Result
+
+	pos int64
+	seeked bool // Read is served by ReadAt after Seek
+	raOnce sync.Once
+	ra *rangeReader
 }
 
-func (f *File) Stat() (*FileInfo /*fs.FileInfo*/, error) {
+func (f *File) Stat() (fs.FileInfo, error) {
 	f.run()
 	if f.Err != nil {
 		return nil, f.Err
@@ -85,23 +231,37 @@ func (f *File) Stat() (*FileInfo /*fs.FileInfo*/, error) {
 }
 
 func (f *File) Read(p []byte) (int, error) {
+	if f.seeked {
+		n, err := f.ReadAt(p, f.pos)
+		f.pos += int64(n)
+		if err == io.EOF && n > 0 {
+			err = nil
+		}
+		return n, err
+	}
 	f.run()
 	if f.Err != nil {
 		return 0, f.Err
 	}
 	if f.Resp.Body == nil {
-		return 0, os.ErrNotExist /*fs.ErrNotExist*/
+		return 0, fs.ErrNotExist
 	}
-	return f.Resp.Body.Read(p)
+	n, err := f.Resp.Body.Read(p)
+	f.pos += int64(n)
+	return n, err
 }
 
 func (f *File) Close() error {
+	if f.ra != nil && f.Status == 0 {
+		// only Range requests are sent
+		return nil
+	}
 	f.run()
 	if f.Err != nil {
 		return f.Err
 	}
 	if f.Resp.Body == nil {
-		return os.ErrNotExist /*fs.ErrNotExist*/
+		return fs.ErrNotExist
 	}
 	return f.Resp.Body.Close()
 }
@@ -129,6 +289,9 @@ type FileInfo struct {
 
 // base name of the file
 func (fi *FileInfo) Name() string {
+	if len(fi.f.name) > 0 {
+		return path.Base(fi.f.name)
+	}
 	fi.parse()
 	if fi.e != nil {
 		return ""
@@ -144,11 +307,13 @@ func (fi *FileInfo) Size() int64 {
 	return fi.f.Resp.ContentLength
 }
 
-// file mode bits
-/*
+// file mode bits, the remote file is read-only
 func (fi *FileInfo) Mode() fs.FileMode {
-	return fs.ModeSocket
-}*/
+	if fi.IsDir() {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
 
 // modification time
 func (fi *FileInfo) ModTime() time.Time {
@@ -156,9 +321,13 @@ func (fi *FileInfo) ModTime() time.Time {
 	return t
 }
 
-// abbreviation for Mode().IsDir()
+// abbreviation for Mode().IsDir(), a file of FS is a directory if it is the root,
+// or its request is redirected to the URL ending with "/".
 func (fi *FileInfo) IsDir() bool {
-	return false
+	if len(fi.f.name) == 0 || fi.f.Resp == nil || fi.f.Resp.Request == nil {
+		return false
+	}
+	return fi.f.name == "." || strings.HasSuffix(fi.f.Resp.Request.URL.Path, "/")
 }
 
 // underlying data source (can return nil)
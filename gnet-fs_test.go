@@ -1,13 +1,16 @@
-// remove go1.16 dependency, build go1.16
-
 package gnet
 
 import (
 	"testing"
+	"net/http"
+	"net/http/httptest"
+	"io/fs"
+	"errors"
+	"strings"
+	"time"
 	"io"
 	"os"
 	"fmt"
-	// "io/fs"
 )
 
 func TestFSGet(t *testing.T) {
@@ -27,6 +30,25 @@ func fs_output(fp *File) {
 	io.Copy(os.Stdout, fp)
 }
 
+func TestFSMethods(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Method)
+	}))
+	defer ts.Close()
+
+	cases := map[string]func(string, ...Option) *File{
+		http.MethodGet: Get, http.MethodPost: Post, http.MethodPut: Put, http.MethodDelete: Delete, http.MethodPatch: Patch,
+	}
+	for method, call := range cases {
+		fp := call(ts.URL)
+		b, err := io.ReadAll(fp)
+		fp.Close()
+		if err != nil || string(b) != method {
+			t.Fatalf("method %s expected, got %s, %v\n", method, b, err)
+		}
+	}
+}
+
 func TestFSParseJSON(t *testing.T) {
 	var res map[string]interface{}
 	status, err := FsCallAndParseJSON("http://httpbin.org/post", "POST", &res, Params(map[string]interface{}{"a": "b", "c": 1}), JSONCall(), BodyLogger(os.Stderr))
@@ -38,3 +60,63 @@ func TestFSParseJSON(t *testing.T) {
 	fmt.Printf("\n---- done to TestFSParseJSON() ---\n\n")
 }
 
+
+func TestFS(t *testing.T) {
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	files := map[string]string{
+		"/dir/hello.txt": "hello fs",
+		"/dir/a b.txt": "with space",
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/dir/secret":
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		content, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, "", modTime, strings.NewReader(content))
+	}))
+	defer ts.Close()
+
+	fsys := NewFS(ts.URL + "/dir/")
+	if b, err := fs.ReadFile(fsys, "hello.txt"); err != nil || string(b) != "hello fs" {
+		t.Fatalf("unexpected %s, %v\n", b, err)
+	}
+	if b, err := fs.ReadFile(fsys, "a b.txt"); err != nil || string(b) != "with space" {
+		t.Fatalf("unexpected %s, %v\n", b, err)
+	}
+	fi, err := fs.Stat(fsys, "hello.txt")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if fi.Name() != "hello.txt" || fi.Size() != 8 || !fi.ModTime().Equal(modTime) || !fi.Mode().IsRegular() {
+		t.Fatalf("unexpected file info %s, %d, %v, %v\n", fi.Name(), fi.Size(), fi.ModTime(), fi.Mode())
+	}
+
+	if _, err = fsys.Open("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("fs.ErrNotExist expected, got %v\n", err)
+	}
+	if _, err = fs.Stat(fsys, "secret"); !errors.Is(err, fs.ErrPermission) {
+		t.Fatalf("fs.ErrPermission expected, got %v\n", err)
+	}
+	if _, err = fsys.Open("../etc/passwd"); !errors.Is(err, fs.ErrInvalid) {
+		t.Fatalf("fs.ErrInvalid expected, got %v\n", err)
+	}
+
+	// rooted at BaseUrl
+	b, _ := NewBaseUrl2(ts.URL + "/dir")
+	if content, err := fs.ReadFile(b.FS(), "hello.txt"); err != nil || string(content) != "hello fs" {
+		t.Fatalf("unexpected %s, %v\n", content, err)
+	}
+
+	// served by http.FileServer
+	fileServer := httptest.NewServer(http.FileServer(http.FS(fsys)))
+	defer fileServer.Close()
+	if _, content, _, err := Http(fileServer.URL + "/hello.txt"); err != nil || string(content) != "hello fs" {
+		t.Fatalf("unexpected %s, %v\n", content, err)
+	}
+}
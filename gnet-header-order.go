@@ -0,0 +1,133 @@
+package gnet
+
+import (
+	"net/textproto"
+	"net/http"
+	"crypto/tls"
+	"context"
+	"strings"
+	"bytes"
+	"net"
+)
+
+// ---- ordered request headers ----
+// net/http writes Host and User-Agent first, then the other headers sorted by key. The head of
+// the request is rewritten in the order of HeaderOrder() while it is written to the connection,
+// so the request is sent with HTTP/1.1 over a connection of its own.
+
+var headEnd = []byte("\r\n\r\n")
+
+// transport writing the headers in order, nil if rt is not a *http.Transport.
+func orderedTransport(rt http.RoundTripper, order []string) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	base, ok := rt.(*http.Transport)
+	if !ok {
+		return nil
+	}
+
+	t := base.Clone()
+	t.DisableKeepAlives = true
+	t.ForceAttemptHTTP2 = false
+	t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	dial := base.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{KeepAlive: dialKeepAlive}).DialContext
+	}
+	t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &orderedHeaderConn{Conn: conn, order: order}, nil
+	}
+	// the head is rewritten before it is encrypted
+	t.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		var cfg *tls.Config
+		if base.TLSClientConfig != nil {
+			cfg = base.TLSClientConfig.Clone()
+		} else {
+			cfg = &tls.Config{}
+		}
+		if len(cfg.ServerName) == 0 {
+			host, _, _ := net.SplitHostPort(addr)
+			cfg.ServerName = host
+		}
+		cfg.NextProtos = []string{"http/1.1"}
+		tlsConn := tls.Client(conn, cfg)
+		if err = tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return &orderedHeaderConn{Conn: tlsConn, order: order}, nil
+	}
+	return t
+}
+
+// orderedHeaderConn buffers the head of the first request written to it, and writes it
+// with the headers in order. The rest is written as it is.
+type orderedHeaderConn struct {
+	net.Conn
+	order []string
+	head []byte
+	written bool
+}
+
+func (c *orderedHeaderConn) Write(p []byte) (int, error) {
+	if c.written {
+		return c.Conn.Write(p)
+	}
+	c.head = append(c.head, p...)
+	pos := bytes.Index(c.head, headEnd)
+	if pos < 0 {
+		return len(p), nil
+	}
+	c.written = true
+	head, rest := c.head[:pos], c.head[pos+len(headEnd):]
+	c.head = nil
+
+	var b bytes.Buffer
+	b.Write(orderHead(head, c.order))
+	b.Write(headEnd)
+	b.Write(rest)
+	if _, err := c.Conn.Write(b.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// the header lines of the keys in order come first, the lines of the same key are kept in order.
+func orderHead(head []byte, order []string) []byte {
+	lines := strings.Split(string(head), "\r\n")
+	requestLine, fields := lines[0], lines[1:]
+	keyOf := func(line string) string {
+		if pos := strings.IndexByte(line, ':'); pos > 0 {
+			return textproto.CanonicalMIMEHeaderKey(line[:pos])
+		}
+		return ""
+	}
+
+	sorted := make([]string, 0, len(lines))
+	sorted = append(sorted, requestLine)
+	used := make([]bool, len(fields))
+	for _, key := range order {
+		key = textproto.CanonicalMIMEHeaderKey(key)
+		for i, line := range fields {
+			if !used[i] && keyOf(line) == key {
+				sorted = append(sorted, line)
+				used[i] = true
+			}
+		}
+	}
+	for i, line := range fields {
+		if !used[i] {
+			sorted = append(sorted, line)
+		}
+	}
+	return []byte(strings.Join(sorted, "\r\n"))
+}
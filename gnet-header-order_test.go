@@ -0,0 +1,78 @@
+package gnet
+
+import (
+	"testing"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"bufio"
+	"fmt"
+	"net"
+)
+
+// server responding the header keys of the request in the order received
+func headerKeysServer(t *testing.T) (string, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				r.ReadString('\n')
+				var keys []string
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil || line == "\r\n" {
+						break
+					}
+					keys = append(keys, line[:strings.IndexByte(line, ':')])
+				}
+				body := strings.Join(keys, ",")
+				fmt.Fprintf(conn, "HTTP/1.1 200 OK\r\nContent-Length: %d\r\nConnection: close\r\n\r\n%s", len(body), body)
+			}()
+		}
+	}()
+	return "http://" + l.Addr().String(), func() { l.Close() }
+}
+
+func TestHeaderOrder(t *testing.T) {
+	url, stop := headerKeysServer(t)
+	defer stop()
+
+	header := http.Header{"X-B": {"b"}, "X-A": {"a"}, "Accept": {"*/*"}}
+	_, content, _, err := Http(url, HttpHeader(header), HeaderOrder("x-b", "Accept", "Host", "X-A"))
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if keys := string(content); !strings.HasPrefix(keys, "X-B,Accept,Host,X-A,") {
+		t.Fatalf("headers in order expected, got %s\n", keys)
+	}
+
+	// sorted by net/http without HeaderOrder()
+	_, content, _, err = Http(url, HttpHeader(header))
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if keys := string(content); !strings.Contains(keys, "Accept,X-A,X-B") {
+		t.Fatalf("sorted headers expected, got %s\n", keys)
+	}
+}
+
+func TestHeaderOrderTLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", r.Proto, r.Header.Get("X-A"))
+	}))
+	defer ts.Close()
+
+	_, content, _, err := Http(ts.URL, AddHeader("X-A", "a"), HeaderOrder("X-A", "Host"))
+	if err != nil || string(content) != "HTTP/1.1 a" {
+		t.Fatalf("unexpected %s, %v\n", content, err)
+	}
+}
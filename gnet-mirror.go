@@ -0,0 +1,119 @@
+package gnet
+
+import (
+	"path/filepath"
+	"net/http"
+	"strings"
+	"time"
+	"fmt"
+	"io"
+	"os"
+)
+
+const etagFileSuffix = ".etag"
+
+// Mirror keeps localPath the same as url, it is replaced only if the content of url is changed,
+// which is checked with If-Modified-Since by the mtime of localPath and If-None-Match by the ETag
+// saved in "localPath.etag". The mtime of localPath is set to Last-Modified. The timeout of WithTimeout()
+// is applied to waiting for the response and every read of the body. changed is true with the error of
+// saving the ETag if localPath is replaced.
+// The options WithProgress() and ExpectChecksum() can be used.
+func Mirror(url, localPath string, options ...Option) (changed bool, err error) {
+	options = options[:len(options):len(options)]
+	if st, e := os.Stat(localPath); e == nil {
+		if st.IsDir() {
+			return false, fmt.Errorf("%s is a directory", localPath)
+		}
+		options = append(options, SetHeader("If-Modified-Since", st.ModTime().UTC().Format(http.TimeFormat)))
+		if etag := readETagFile(localPath); len(etag) > 0 {
+			options = append(options, SetHeader("If-None-Match", etag))
+		}
+	}
+	option := getOptions(options...)
+	option.streaming = true
+	option.idleTimeout = true
+	option.dontReadRespBody = true
+	option.method = http.MethodGet
+
+	status, _, resp, err := http_i(url, option)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch status {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return false, nil
+	default:
+		return false, fmt.Errorf("failed to mirror %s: status %d", url, status)
+	}
+
+	if err = saveMirror(localPath, resp, option); err != nil {
+		return false, err
+	}
+	// localPath is replaced already
+	return true, writeETagFile(localPath, resp.Header.Get("ETag"))
+}
+
+// the content is written to a temp file in the same dir, which is renamed to localPath after completed.
+func saveMirror(localPath string, resp *http.Response, option *Options) error {
+	fp, err := os.CreateTemp(filepath.Dir(localPath), "." + filepath.Base(localPath) + ".tmp-")
+	if err != nil {
+		return err
+	}
+	tmpFile := fp.Name()
+	defer os.Remove(tmpFile)
+
+	var w io.Writer = fp
+	if option.progress != nil {
+		w = &progressWriter{w: fp, total: resp.ContentLength, progress: option.progress}
+		option.progress(0, resp.ContentLength)
+	}
+	n, err := io.Copy(w, resp.Body)
+	if e := fp.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		return err
+	}
+	if resp.ContentLength >= 0 && n != resp.ContentLength {
+		return fmt.Errorf("incomplete content: %d of %d bytes", n, resp.ContentLength)
+	}
+	if err = verifyChecksum(tmpFile, option, resp.Header); err != nil {
+		return err
+	}
+
+	// the mode of the temp file is 0600, the mode of localPath is kept
+	var mode os.FileMode = 0644
+	if st, e := os.Stat(localPath); e == nil {
+		mode = st.Mode().Perm()
+	}
+	if err = os.Chmod(tmpFile, mode); err != nil {
+		return err
+	}
+	if lastModified, e := GetLastModified(resp); e == nil {
+		if err = os.Chtimes(tmpFile, time.Now(), lastModified); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmpFile, localPath)
+}
+
+func readETagFile(localPath string) string {
+	b, err := os.ReadFile(localPath + etagFileSuffix)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+func writeETagFile(localPath, etag string) error {
+	if len(etag) == 0 {
+		if err := os.Remove(localPath + etagFileSuffix); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return os.WriteFile(localPath + etagFileSuffix, []byte(etag), 0644)
+}
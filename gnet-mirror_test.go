@@ -0,0 +1,149 @@
+package gnet
+
+import (
+	"testing"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+	"os"
+)
+
+func TestMirror(t *testing.T) {
+	var version, sent int32 = 1, 0
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/file.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		v := atomic.LoadInt32(&version)
+		content := strings.Repeat("v", int(v))
+		w.Header().Set("ETag", `"` + content + `"`)
+		rs := strings.NewReader(content)
+		if r.Header.Get("If-None-Match") != `"` + content + `"` {
+			atomic.AddInt32(&sent, 1)
+		}
+		http.ServeContent(w, r, "file.txt", modTime.Add(time.Duration(v) * time.Minute), rs)
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "gnet-mirror")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer os.RemoveAll(dir)
+	localPath := filepath.Join(dir, "file.txt")
+
+	check := func(expectedChanged bool, expected string, v int32) {
+		t.Helper()
+		changed, err := Mirror(ts.URL + "/file.txt", localPath)
+		if err != nil || changed != expectedChanged {
+			t.Fatalf("changed %v expected, got %v, %v\n", expectedChanged, changed, err)
+		}
+		content, err := os.ReadFile(localPath)
+		if err != nil || string(content) != expected {
+			t.Fatalf("unexpected %s, %v\n", content, err)
+		}
+		st, _ := os.Stat(localPath)
+		if !st.ModTime().Equal(modTime.Add(time.Duration(v) * time.Minute)) {
+			t.Fatalf("unexpected mtime %v\n", st.ModTime())
+		}
+		if etag := readETagFile(localPath); etag != `"` + expected + `"` {
+			t.Fatalf("unexpected ETag %s\n", etag)
+		}
+	}
+
+	check(true, "v", 1)
+	check(false, "v", 1)
+	atomic.StoreInt32(&version, 2)
+	check(true, "vv", 2)
+	check(false, "vv", 2)
+	if n := atomic.LoadInt32(&sent); n != 2 {
+		t.Fatalf("content expected to be sent 2 times, got %d\n", n)
+	}
+
+	if _, err := Mirror(ts.URL + "/not-found", localPath); err == nil {
+		t.Fatalf("error expected\n")
+	}
+	if content, _ := os.ReadFile(localPath); string(content) != "vv" {
+		t.Fatalf("file should not be replaced, got %s\n", content)
+	}
+}
+
+func TestMirrorFileMode(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("content"))
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "gnet-mirror")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer os.RemoveAll(dir)
+
+	newFile := filepath.Join(dir, "new.txt")
+	if _, err := Mirror(ts.URL, newFile); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if st, err := os.Stat(newFile); err != nil {
+		t.Fatalf("%v\n", err)
+	} else if st.Mode().Perm() != 0644 {
+		t.Fatalf("mode 0644 expected for a new file, got %v\n", st.Mode().Perm())
+	}
+
+	oldFile := filepath.Join(dir, "old.txt")
+	if err := os.WriteFile(oldFile, []byte("old"), 0640); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	os.Chmod(oldFile, 0640)
+	if changed, err := Mirror(ts.URL, oldFile); err != nil || !changed {
+		t.Fatalf("changed expected, got %v, %v\n", changed, err)
+	}
+	if st, err := os.Stat(oldFile); err != nil {
+		t.Fatalf("%v\n", err)
+	} else if st.Mode().Perm() != 0640 {
+		t.Fatalf("mode 0640 expected to be kept, got %v\n", st.Mode().Perm())
+	}
+}
+
+func TestMirrorTimeout(t *testing.T) {
+	stalled := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-stalled
+	}))
+	defer ts.Close()
+	defer close(stalled)
+
+	start := time.Now()
+	if _, err := Mirror(ts.URL, filepath.Join(t.TempDir(), "file.txt"), WithTimeoutDuration(200*time.Millisecond)); err == nil {
+		t.Fatalf("timeout expected\n")
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Fatalf("timeout expected to be applied, took %v\n", d)
+	}
+}
+
+func TestMirrorETagError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("content"))
+	}))
+	defer ts.Close()
+
+	localPath := filepath.Join(t.TempDir(), "file.txt")
+	// the ETag file can't be written
+	if err := os.Mkdir(localPath + etagFileSuffix, 0755); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	changed, err := Mirror(ts.URL, localPath)
+	if !changed || err == nil {
+		t.Fatalf("changed with error expected, got %v, %v\n", changed, err)
+	}
+	if content, _ := os.ReadFile(localPath); string(content) != "content" {
+		t.Fatalf("unexpected %s\n", content)
+	}
+}
@@ -0,0 +1,187 @@
+package gnet
+
+import (
+	"mime/multipart"
+	"net/textproto"
+	"path/filepath"
+	"io/fs"
+	"mime"
+	"strings"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	mimeOctetStream = "application/octet-stream"
+)
+
+var (
+	quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+)
+
+// multipart/form-data params, it can be used as Params(Multipart(...))
+type MultipartParams struct {
+	parts    []*MultipartPart
+	boundary string
+}
+
+// a text field or a file of multipart/form-data
+type MultipartPart struct {
+	fieldName   string
+	value       string
+	isFile      bool
+	fileName    string
+	contentType string
+	open        func() (io.Reader, func() error, error)
+}
+
+type PartOption func(*MultipartPart)
+
+func Multipart(parts ...*MultipartPart) *MultipartParams {
+	return &MultipartParams{
+		parts: parts,
+		boundary: multipart.NewWriter(io.Discard).Boundary(),
+	}
+}
+
+func (m *MultipartParams) Add(parts ...*MultipartPart) *MultipartParams {
+	m.parts = append(m.parts, parts...)
+	return m
+}
+
+func (m *MultipartParams) FormDataContentType() string {
+	return fmt.Sprintf("multipart/form-data; boundary=%s", m.boundary)
+}
+
+// text field
+func FormField(fieldName, value string) *MultipartPart {
+	return &MultipartPart{
+		fieldName: fieldName,
+		value: value,
+	}
+}
+
+// file part with the content of filePath, which will be opened every time the body is sent.
+func FormFile(fieldName, filePath string, options ...PartOption) *MultipartPart {
+	p := &MultipartPart{
+		fieldName: fieldName,
+		isFile: true,
+		fileName: filepath.Base(filePath),
+		open: func() (io.Reader, func() error, error) {
+			fp, err := os.Open(filePath)
+			if err != nil {
+				return nil, nil, err
+			}
+			return fp, fp.Close, nil
+		},
+	}
+	return p.apply(options...)
+}
+
+// file part with the content of r. r can be sent only once unless it is an io.Seeker.
+func FormReader(fieldName, fileName string, r io.Reader, options ...PartOption) *MultipartPart {
+	consumed := false
+	p := &MultipartPart{
+		fieldName: fieldName,
+		isFile: true,
+		fileName: fileName,
+		open: func() (io.Reader, func() error, error) {
+			if consumed {
+				s, ok := r.(io.Seeker)
+				if !ok {
+					return nil, nil, fmt.Errorf("reader of part %s can not be replayed", fieldName)
+				}
+				if _, err := s.Seek(0, io.SeekStart); err != nil {
+					return nil, nil, err
+				}
+			}
+			consumed = true
+			return r, func() error { return nil }, nil
+		},
+	}
+	return p.apply(options...)
+}
+
+// file part with the content of name in fsys.
+func FormFSFile(fieldName string, fsys fs.FS, name string, options ...PartOption) *MultipartPart {
+	p := &MultipartPart{
+		fieldName: fieldName,
+		isFile: true,
+		fileName: filepath.Base(name),
+		open: func() (io.Reader, func() error, error) {
+			fp, err := fsys.Open(name)
+			if err != nil {
+				return nil, nil, err
+			}
+			return fp, fp.Close, nil
+		},
+	}
+	return p.apply(options...)
+}
+
+func PartFileName(fileName string) PartOption {
+	return func(p *MultipartPart) {
+		p.fileName = fileName
+	}
+}
+
+func PartContentType(contentType string) PartOption {
+	return func(p *MultipartPart) {
+		p.contentType = contentType
+	}
+}
+
+func (p *MultipartPart) apply(options ...PartOption) *MultipartPart {
+	for _, o := range options {
+		o(p)
+	}
+	if len(p.contentType) == 0 {
+		if p.contentType = mime.TypeByExtension(filepath.Ext(p.fileName)); len(p.contentType) == 0 {
+			p.contentType = mimeOctetStream
+		}
+	}
+	return p
+}
+
+func (p *MultipartPart) writeTo(w *multipart.Writer) error {
+	if !p.isFile {
+		return w.WriteField(p.fieldName, p.value)
+	}
+
+	r, closeFn, err := p.open()
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, escapeQuotes(p.fieldName), escapeQuotes(p.fileName)))
+	h.Set(headerContentType, p.contentType)
+	pw, err := w.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(pw, r)
+	return err
+}
+
+func (m *MultipartParams) write(out io.Writer) error {
+	w := multipart.NewWriter(out)
+	w.SetBoundary(m.boundary)
+	for _, p := range m.parts {
+		if err := p.writeTo(w); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// the body is streamed through an io.Pipe, and generated again when it is resent.
+func (m *MultipartParams) newBody() *streamBody {
+	return StreamFunc(m.write, -1).newBody()
+}
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
@@ -91,7 +91,7 @@ func (b *BaseUrl) httpCall(uri string, option *Options) (status int, content []b
 		option.method = http.MethodGet
 	}
 
-	if isHttpUrl(uri) {
+	if isAbsUrl(uri) {
 		var req *Request
 		if req, err = newRequest(uri, option); err != nil {
 			return
@@ -100,8 +100,8 @@ func (b *BaseUrl) httpCall(uri string, option *Options) (status int, content []b
 	}
 
 	var paramsReader io.ReadSeeker
-	var header map[string]string
-	if uri, option.method, paramsReader, header, err = adjustHttpArgs(uri, option.method, option.params, option.headers, option.bodyLogger); err != nil {
+	var header http.Header
+	if uri, option.method, paramsReader, header, err = adjustHttpArgs(uri, option.method, option.params, option.mergeHeader(option.headers), option); err != nil {
 		return
 	}
 
@@ -113,7 +113,7 @@ func (b *BaseUrl) jsonCall(uri string, option *Options) (status int, content []b
 		option.method = http.MethodPost
 	}
 
-	if isHttpUrl(uri) {
+	if isAbsUrl(uri) {
 		var req *Request
 		if req, err = newRequest(uri, option); err != nil {
 			return
@@ -122,8 +122,8 @@ func (b *BaseUrl) jsonCall(uri string, option *Options) (status int, content []b
 	}
 
 	var paramsReader io.ReadSeeker
-	var header map[string]string
-	if option.method, paramsReader, header, err = adjustJsonArgs(option.method, option.params, option.headers, option.bodyLogger); err != nil {
+	var header http.Header
+	if uri, option.method, paramsReader, header, err = adjustJsonArgs(uri, option.method, option.params, option.mergeHeader(option.headers), option); err != nil {
 		return
 	}
 
@@ -131,7 +131,7 @@ func (b *BaseUrl) jsonCall(uri string, option *Options) (status int, content []b
 }
 
 func (b *BaseUrl) getWithBody(uri string, option *Options) (status int, content []byte, resp *http.Response, err error) {
-	if isHttpUrl(uri) {
+	if isAbsUrl(uri) {
 		var req *Request
 		if req, err = newRequest(uri, option); err != nil {
 			return
@@ -140,10 +140,10 @@ func (b *BaseUrl) getWithBody(uri string, option *Options) (status int, content
 	}
 
 	var paramsReader io.ReadSeeker
-	var header map[string]string
+	var header http.Header
 
 	// using http.MethodPost to make a trick
-	if _, _, paramsReader, header, err = adjustHttpArgs(uri, http.MethodPost, option.params, option.headers, option.bodyLogger); err != nil {
+	if uri, _, paramsReader, header, err = adjustHttpArgs(uri, http.MethodPost, option.params, option.mergeHeader(option.headers), option); err != nil {
 		return
 	}
 
@@ -151,37 +151,32 @@ func (b *BaseUrl) getWithBody(uri string, option *Options) (status int, content
 	return b.run(uri, paramsReader, header, option)
 }
 
-func (b *BaseUrl) run(uri string, paramsReader io.ReadSeeker, header map[string]string, option *Options) (status int, content []byte, resp *http.Response, err error) {
-	var req *Request
-	startIdx := b.pick()
-	for i:=startIdx; i<len(b.baseItems); i++ {
-		url := fmt.Sprintf("%s%s", b.baseItems[i].baseUrl, uri)
-		if paramsReader != nil {
-			paramsReader.Seek(0, io.SeekStart)
-		}
-		if req, err = newRequest(url, option); err != nil {
-			return
-		}
-		status, content, resp, err = req.run(url, option.method, paramsReader, header)
-		if err == nil {
-			return
-		}
-	}
-	for i:=0; i<startIdx; i++ {
-		url := fmt.Sprintf("%s%s", b.baseItems[i].baseUrl, uri)
+func (b *BaseUrl) run(uri string, paramsReader io.ReadSeeker, header http.Header, option *Options) (status int, content []byte, resp *http.Response, err error) {
+	b.eachBase(func(baseUrl string) bool {
+		url := fmt.Sprintf("%s%s", baseUrl, uri)
 		if paramsReader != nil {
-			paramsReader.Seek(0, io.SeekStart)
+			if _, err = paramsReader.Seek(0, io.SeekStart); err != nil {
+				return false
+			}
 		}
+		var req *Request
 		if req, err = newRequest(url, option); err != nil {
-			return
+			return false
 		}
 		status, content, resp, err = req.run(url, option.method, paramsReader, header)
-		if err == nil {
+		return err != nil
+	})
+	return
+}
+
+// call is made with the base URLs one by one, starting from a picked one, until it returns false.
+func (b *BaseUrl) eachBase(call func(baseUrl string) (next bool)) {
+	startIdx := b.pick()
+	for i:=0; i<len(b.baseItems); i++ {
+		if !call(b.baseItems[(startIdx+i)%len(b.baseItems)].baseUrl) {
 			return
 		}
 	}
-
-	return
 }
 
 func (b *BaseUrl) pick() int {
@@ -189,16 +184,16 @@ func (b *BaseUrl) pick() int {
 }
 
 func (b *BaseUrl) caclWeights() error {
-	if !isHttpUrl(b.baseItems[0].baseUrl) {
-		return fmt.Errorf("prefix of base URL %s is not http or https", b.baseItems[0].baseUrl)
+	if !isAbsUrl(b.baseItems[0].baseUrl) {
+		return fmt.Errorf("scheme of base URL %s is not supported", b.baseItems[0].baseUrl)
 	}
 	allNoWeight := (b.baseItems[0].weight == 0)
 	c := len(b.baseItems)
 
 	for i:=1; i<c; i++ {
 		bi := b.baseItems[i]
-		if !isHttpUrl(bi.baseUrl) {
-			return fmt.Errorf("prefix of base URL %s is not http or https", bi.baseUrl)
+		if !isAbsUrl(bi.baseUrl) {
+			return fmt.Errorf("scheme of base URL %s is not supported", bi.baseUrl)
 		}
 		if bi.weight > 0 {
 			if allNoWeight {
@@ -0,0 +1,194 @@
+package gnet
+
+import (
+	"net/http"
+	"net/url"
+	"encoding/json"
+	"strings"
+	"time"
+	"sync"
+	"fmt"
+	"io"
+)
+
+const (
+	// a token is refreshed earlier than it expires
+	tokenExpiryDelta = 10 * time.Second
+)
+
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ExpiresIn    int64     `json:"expires_in,omitempty"`
+	Expiry       time.Time `json:"-"`
+}
+
+func (t *Token) valid() bool {
+	if t == nil || len(t.AccessToken) == 0 {
+		return false
+	}
+	return t.Expiry.IsZero() || time.Now().Add(tokenExpiryDelta).Before(t.Expiry)
+}
+
+// TokenSource supplies the token injected as "Authorization: Bearer xxx".
+type TokenSource interface {
+	Token() (*Token, error)
+}
+
+// a TokenSource implementing it will be asked to drop the token rejected with 401.
+type TokenInvalidator interface {
+	Invalidate(token *Token)
+}
+
+// the token is sent with all the calls using this option, including those of BaseUrl.
+func WithTokenSource(ts TokenSource) Option {
+	return func(options *Options) {
+		options.tokenSource = ts
+	}
+}
+
+// a TokenSource returning the same token, e.g. a personal access token.
+func StaticToken(accessToken string) TokenSource {
+	return &staticTokenSource{t: &Token{AccessToken: accessToken, TokenType: "Bearer"}}
+}
+
+type staticTokenSource struct {
+	t *Token
+}
+
+func (s *staticTokenSource) Token() (*Token, error) {
+	return s.t, nil
+}
+
+// ---- cached token source ----
+
+// CachedTokenSource caches the token of fetch until it is about to expire,
+// concurrent callers share the same refreshing.
+type CachedTokenSource struct {
+	fetch func() (*Token, error)
+	mu sync.Mutex
+	t  *Token
+}
+
+func NewCachedTokenSource(fetch func() (*Token, error)) *CachedTokenSource {
+	return &CachedTokenSource{fetch: fetch}
+}
+
+func (c *CachedTokenSource) Token() (*Token, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.t.valid() {
+		return c.t, nil
+	}
+	t, err := c.fetch()
+	if err != nil {
+		return nil, err
+	}
+	c.t = t
+	return t, nil
+}
+
+func (c *CachedTokenSource) Invalidate(t *Token) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.t == t {
+		c.t = nil
+	}
+}
+
+// ---- OAuth2 flows ----
+type OAuth2Config struct {
+	TokenUrl     string
+	ClientId     string
+	ClientSecret string
+	Scopes       []string
+	AuthInParams bool     // send client id and secret as params instead of basic auth
+	Options      []Option // options to call the token endpoint
+}
+
+// OAuth2 client credentials flow
+func ClientCredentials(conf *OAuth2Config) *CachedTokenSource {
+	return NewCachedTokenSource(func() (*Token, error) {
+		params := url.Values{"grant_type": {"client_credentials"}}
+		return conf.requestToken(params)
+	})
+}
+
+// OAuth2 refresh token flow, the refresh token is replaced if a new one is issued.
+func RefreshToken(conf *OAuth2Config, refreshToken string) *CachedTokenSource {
+	var mu sync.Mutex
+	return NewCachedTokenSource(func() (*Token, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		params := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {refreshToken}}
+		t, err := conf.requestToken(params)
+		if err != nil {
+			return nil, err
+		}
+		if len(t.RefreshToken) > 0 {
+			refreshToken = t.RefreshToken
+		}
+		return t, nil
+	})
+}
+
+func (conf *OAuth2Config) requestToken(params url.Values) (*Token, error) {
+	if len(conf.Scopes) > 0 {
+		params.Set("scope", strings.Join(conf.Scopes, " "))
+	}
+	options := make([]Option, 0, len(conf.Options) + 4)
+	options = append(options, conf.Options...)
+	if conf.AuthInParams {
+		params.Set("client_id", conf.ClientId)
+		params.Set("client_secret", conf.ClientSecret)
+	} else {
+		options = append(options, BasicAuth(url.QueryEscape(conf.ClientId), url.QueryEscape(conf.ClientSecret)))
+	}
+	options = append(options, M(http.MethodPost), Params(params), SetHeader("Accept", "application/json"))
+
+	status, content, _, err := Http(conf.TokenUrl, options...)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("failed to get token: status %d, %s", status, content)
+	}
+	var t Token
+	if err = json.Unmarshal(content, &t); err != nil {
+		return nil, err
+	}
+	if len(t.AccessToken) == 0 {
+		return nil, fmt.Errorf("no access_token in response of token endpoint")
+	}
+	if t.ExpiresIn > 0 {
+		t.Expiry = time.Now().Add(time.Duration(t.ExpiresIn) * time.Second)
+	}
+	return &t, nil
+}
+
+// ---- authenticator with bearer token ----
+type bearerAuth struct {
+	ts TokenSource
+	t  *Token
+}
+
+func (a *bearerAuth) authorize(req *http.Request, body io.ReadSeeker) error {
+	t, err := a.ts.Token()
+	if err != nil {
+		return err
+	}
+	a.t = t
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", t.AccessToken))
+	return nil
+}
+
+func (a *bearerAuth) retry(resp *http.Response) bool {
+	inv, ok := a.ts.(TokenInvalidator)
+	if !ok {
+		return false
+	}
+	inv.Invalidate(a.t)
+	return true
+}
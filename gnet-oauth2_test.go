@@ -0,0 +1,55 @@
+package gnet
+
+import (
+	"testing"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"sync"
+	"fmt"
+)
+
+func TestClientCredentials(t *testing.T) {
+	var issued int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			if id, secret, _ := r.BasicAuth(); id != "id" || secret != "secret" || r.FormValue("grant_type") != "client_credentials" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			n := atomic.AddInt32(&issued, 1)
+			fmt.Fprintf(w, `{"access_token":"t%d","token_type":"Bearer","expires_in":3600}`, n)
+		default:
+			// the first token is revoked
+			if r.Header.Get("Authorization") != "Bearer t2" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprintf(w, "ok")
+		}
+	}))
+	defer ts.Close()
+
+	tokenSource := ClientCredentials(&OAuth2Config{TokenUrl: ts.URL + "/token", ClientId: "id", ClientSecret: "secret"})
+	status, content, _, err := Http(ts.URL + "/api", WithTokenSource(tokenSource))
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if status != http.StatusOK || string(content) != "ok" {
+		t.Fatalf("unexpected response: %d %s\n", status, content)
+	}
+
+	var wg sync.WaitGroup
+	for i:=0; i<10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			Http(ts.URL + "/api", WithTokenSource(tokenSource))
+		}()
+	}
+	wg.Wait()
+	if issued != 2 {
+		t.Fatalf("token is expected to be issued twice, but %d\n", issued)
+	}
+}
@@ -1,25 +1,57 @@
 package gnet
 
 import (
+	"net/http"
+	"context"
 	"time"
 	"io"
 	"os"
+	"strings"
+	"fmt"
+	"net"
 )
 
 type Options struct {
 	method   string
 	timeout  time.Duration // timeout to wait while connect/send/recv-ing
 	dontReadRespBody bool  // if it is true, it's your resposibility to get body from http.Response.Body
+	streaming bool         // timeout is not applied to reading the body
+	idleTimeout bool       // with streaming, timeout is applied to the response headers and every read of the body
+	ctx context.Context
 	bodyLogger  io.Writer  // copy body to bodyLogger if not nil
 	multiBase  *BaseUrl
 	dontCheckRedirect bool
+	cookieJar http.CookieJar
 
 	params interface{}
+	paramsIn paramsLocation
+	query  interface{}
+	pathParams map[string]interface{}
+	uriTemplate string
 	headers map[string]string
+	header  http.Header
+	replacedHeaders map[string]bool
+	headerOrder []string
+	host    string
 	jsonCall bool
+	formEncoder formEncoder
 
 	baUser, baPasswd string
 	basicAuth bool
+	credentialProviders []CredentialProvider
+	tokenSource TokenSource
+	daUser, daPasswd string
+	digestAuth bool
+	signer Signer
+
+	progress func(downloaded, total int64)
+	checksumAlgorithm, checksum string
+	maxRetries int
+	maxRetriesSet bool
+
+	unixSocket string
+	dial dialConfig
+	cache Cache
 
 	caCert []byte
 	certPEMBlock, keyPEMBlock []byte
@@ -27,6 +59,13 @@ type Options struct {
 
 type Option func(*Options)
 
+type paramsLocation int
+const (
+	paramsByMethod paramsLocation = iota
+	paramsInQuery
+	paramsInBody
+)
+
 func BasicAuth(userName, password string) Option {
 	return func(options *Options) {
 		options.baUser = userName
@@ -35,18 +74,137 @@ func BasicAuth(userName, password string) Option {
 	}
 }
 
+// the providers are consulted in turn for the host of every request unless BasicAuth() is given.
+func WithCredentialProvider(providers ...CredentialProvider) Option {
+	return func(options *Options) {
+		options.credentialProviders = append(options.credentialProviders, providers...)
+	}
+}
+
+// RFC 7616 digest authentication, the challenge of 401 response is answered automatically.
+func DigestAuth(userName, password string) Option {
+	return func(options *Options) {
+		options.daUser = userName
+		options.daPasswd = password
+		options.digestAuth = true
+	}
+}
+
+// signer is called every time after the request is built, see Signer
+func WithSigner(signer Signer) Option {
+	return func(options *Options) {
+		options.signer = signer
+	}
+}
+
 func Params(params interface{}) Option {
 	return func(options *Options) {
 		options.params = params
 	}
 }
 
+// query params appended to the URL for any method, it accepts the same types as Params().
+func Query(query interface{}) Option {
+	return func(options *Options) {
+		options.query = query
+	}
+}
+
+// values of the placeholders in URL, e.g. {"id": 1} for "/users/{id}"
+func PathParams(pathParams map[string]interface{}) Option {
+	return func(options *Options) {
+		options.pathParams = pathParams
+	}
+}
+
+// send Params() as the query string whatever the method is
+func ParamsInQuery() Option {
+	return func(options *Options) {
+		options.paramsIn = paramsInQuery
+	}
+}
+
+// send Params() as the body whatever the method is
+func ParamsInBody() Option {
+	return func(options *Options) {
+		options.paramsIn = paramsInBody
+	}
+}
+
 func Headers(headers map[string]string) Option {
 	return func(options *Options) {
 		options.headers = headers
 	}
 }
 
+// how to encode slices in form params, ArrayRepeat is the default.
+func FormArrayFormat(arrayFormat ArrayFormat) Option {
+	return func(options *Options) {
+		options.formEncoder.arrayFormat = arrayFormat
+	}
+}
+
+// layout to format time.Time in form params, time.RFC3339 is the default.
+func FormTimeLayout(layout string) Option {
+	return func(options *Options) {
+		options.formEncoder.timeLayout = layout
+	}
+}
+
+// multi-valued headers, values of the same key are sent in the given order.
+// the keys are sorted by net/http, HeaderOrder() can be used to send them in order.
+func HttpHeader(header http.Header) Option {
+	return func(options *Options) {
+		if options.header == nil {
+			options.header = http.Header{}
+		}
+		for k, v := range header {
+			for _, vv := range v {
+				options.header.Add(k, vv)
+			}
+		}
+	}
+}
+
+// add a value to the header key, the existing values are kept
+func AddHeader(key, value string) Option {
+	return func(options *Options) {
+		if options.header == nil {
+			options.header = http.Header{}
+		}
+		options.header.Add(key, value)
+	}
+}
+
+// replace all values of the header key
+func SetHeader(key, value string) Option {
+	return func(options *Options) {
+		if options.header == nil {
+			options.header = http.Header{}
+		}
+		options.header.Set(key, value)
+		if options.replacedHeaders == nil {
+			options.replacedHeaders = map[string]bool{}
+		}
+		options.replacedHeaders[http.CanonicalHeaderKey(key)] = true
+	}
+}
+
+// send the headers of keys first in the given order, e.g. HeaderOrder("Host", "User-Agent", "Accept"),
+// the others follow them. The request is sent with HTTP/1.1 over a new connection.
+func HeaderOrder(keys ...string) Option {
+	return func(options *Options) {
+		options.headerOrder = keys
+	}
+}
+
+// override the Host header sent to the server
+func Host(host string) Option {
+	return func(options *Options) {
+		options.host = host
+	}
+}
+
 func JSONCall() Option {
 	return func(options *Options) {
 		options.jsonCall = true
@@ -71,6 +229,13 @@ func WithTimeoutDuration(timeout time.Duration) Option {
 	}
 }
 
+// the request is canceled when ctx is done
+func WithContext(ctx context.Context) Option {
+	return func(options *Options) {
+		options.ctx = ctx
+	}
+}
+
 func DontReadRespBody() Option {
 	return func(options *Options) {
 		options.dontReadRespBody = true
@@ -95,6 +260,103 @@ func DontCheckRedirect() Option {
 	}
 }
 
+// cookies are kept in jar between calls using it, see also NewSession() and NewCookieJar()
+func WithCookieJar(jar http.CookieJar) Option {
+	return func(options *Options) {
+		options.cookieJar = jar
+	}
+}
+
+// progress of Download(), total is -1 if it is unknown
+func WithProgress(progress func(downloaded, total int64)) Option {
+	return func(options *Options) {
+		options.progress = progress
+	}
+}
+
+// checksum in hex verified by Download(), algorithm is "sha256" or "md5"
+func ExpectChecksum(algorithm, checksum string) Option {
+	return func(options *Options) {
+		options.checksumAlgorithm = algorithm
+		options.checksum = checksum
+	}
+}
+
+// times to retry after failures, 0 to disable retrying
+func MaxRetries(maxRetries int) Option {
+	return func(options *Options) {
+		if maxRetries < 0 {
+			maxRetries = 0
+		}
+		options.maxRetries = maxRetries
+		options.maxRetriesSet = true
+	}
+}
+
+// send requests to the unix socket, the host of URL is only used as the Host header.
+func WithUnixSocket(socketPath string) Option {
+	return func(options *Options) {
+		options.unixSocket = socketPath
+	}
+}
+
+// cache the GET responses in cache, e.g. NewMemoryCache() or NewDiskCache(), following RFC 9111.
+// The streaming requests are not cached.
+func WithCache(cache Cache) Option {
+	return func(options *Options) {
+		options.cache = cache
+	}
+}
+
+// connect to addr, an IP or a host name, instead of the address of host, which is "host" or "host:port".
+// Only the dialed address is changed, the Host header and TLS SNI keep the host of URL.
+func ResolveHost(host, addr string) Option {
+	return func(options *Options) {
+		hosts := make(map[string]string, len(options.dial.hosts)+1)
+		for h, a := range options.dial.hosts {
+			hosts[h] = a
+		}
+		hosts[strings.ToLower(host)] = addr
+		options.dial.hosts = hosts
+	}
+}
+
+// resolve host names with resolver instead of net.DefaultResolver.
+func WithResolver(resolver *net.Resolver) Option {
+	return func(options *Options) {
+		options.dial.resolver = resolver
+		options.dial.resolverKey = fmt.Sprintf("%p", resolver)
+	}
+}
+
+// resolve host names with the DNS server addr, "ip" or "ip:port".
+func DNSServer(addr string) Option {
+	return func(options *Options) {
+		options.dial.resolver = newDNSResolver(addr)
+		options.dial.resolverKey = "dns:" + addr
+	}
+}
+
+func WithIPPreference(preference IPPreference) Option {
+	return func(options *Options) {
+		options.dial.ipPreference = preference
+	}
+}
+
+// bind the local address ip when dialing, only addresses of the same family are dialed.
+func LocalAddr(ip net.IP) Option {
+	return func(options *Options) {
+		options.dial.localAddr = ip
+	}
+}
+
+// cache the resolved addresses for ttl, the cache is shared by all the requests.
+func DNSCacheTTL(ttl time.Duration) Option {
+	return func(options *Options) {
+		options.dial.dnsTTL = ttl
+	}
+}
+
 func WithTLSCertFiles(certPemFile, keyPemFile string) Option {
 	return func(options *Options) {
 		if certPEMBlock, err := os.ReadFile(certPemFile); err == nil {
@@ -126,6 +388,42 @@ func WithCaCert(caCert []byte) Option {
 	}
 }
 
+// whether Params() are sent as the query string. By default only methods without
+// a body, i.e. GET, HEAD, OPTIONS and TRACE, send params in the query string.
+func (option *Options) paramsInQuery(method string) bool {
+	switch option.paramsIn {
+	case paramsInQuery:
+		return true
+	case paramsInBody:
+		return false
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+// headers set by Headers() and the other header options are merged into a new http.Header,
+// values of HttpHeader()/AddHeader() are added after those of Headers(). The order of values
+// within a key is kept, the order of keys is set by HeaderOrder().
+func (option *Options) mergeHeader(headers map[string]string) http.Header {
+	h := make(http.Header, len(headers) + len(option.header))
+	for k, v := range headers {
+		h.Set(k, v)
+	}
+	for k, v := range option.header {
+		if option.replacedHeaders[k] {
+			// SetHeader() overrides the value of Headers()
+			h[k] = append([]string(nil), v...)
+		} else {
+			h[k] = append(h[k], v...)
+		}
+	}
+	return h
+}
+
 const (
 	connect_timeout = 5    // default seconds to wait while trying to connect
 )
@@ -143,3 +441,21 @@ func getOptions(options ...Option) *Options {
 	return &option
 }
 
+// copy of option with more options applied, the headers of option are not changed.
+func cloneOptions(option *Options, options ...Option) *Options {
+	o := *option
+	if option.header != nil {
+		o.header = option.header.Clone()
+	}
+	if option.replacedHeaders != nil {
+		o.replacedHeaders = make(map[string]bool, len(option.replacedHeaders))
+		for k, v := range option.replacedHeaders {
+			o.replacedHeaders[k] = v
+		}
+	}
+	for _, opt := range options {
+		opt(&o)
+	}
+	return &o
+}
+
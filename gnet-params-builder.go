@@ -16,26 +16,41 @@ const (
 	mimeJSON = "application/json; charset=UTF-8"
 )
 
-func buildHttpParams(params interface{}, bodyLogger io.Writer) (io.ReadSeeker, error) {
+func buildHttpParams(params interface{}, bodyLogger io.Writer, enc *formEncoder) (io.ReadSeeker, error) {
 	if params == nil {
 		return nil, nil
 	}
 	switch v := params.(type) {
+	case *MultipartParams:
+		if bodyLogger != nil {
+			fmt.Fprintf(bodyLogger, "HTTP params: [%s]\n", v.FormDataContentType())
+		}
+		return v.newBody(), nil
+	case *BodyStream:
+		if bodyLogger != nil {
+			fmt.Fprintf(bodyLogger, "HTTP params: [stream]\n")
+		}
+		return v.newBody(), nil
 	case io.ReadSeeker:
 		return v, nil
-	default:
-		param, err := buildHttpStringParams(params, bodyLogger)
-		if err != nil {
-			return nil, err
-		}
-		if len(param) == 0 {
-			return nil, nil
+	case io.Reader:
+		if _, ok := v.(io.WriterTo); !ok {
+			// streamed instead of being read into memory
+			return buildHttpParams(Stream(v, -1), bodyLogger, enc)
 		}
-		return strings.NewReader(param), nil
 	}
+
+	param, err := buildHttpStringParams(params, bodyLogger, enc)
+	if err != nil {
+		return nil, err
+	}
+	if len(param) == 0 {
+		return nil, nil
+	}
+	return strings.NewReader(param), nil
 }
 
-func buildHttpStringParams(params interface{}, bodyLogger io.Writer) (string, error) {
+func buildHttpStringParams(params interface{}, bodyLogger io.Writer, enc *formEncoder) (string, error) {
 	var r string
 	defer func() {
 		if bodyLogger != nil {
@@ -70,13 +85,6 @@ func buildHttpStringParams(params interface{}, bodyLogger io.Writer) (string, er
 	case string:
 		r = v
 		return r, nil
-	case map[string]interface{}:
-		u := url.Values{}
-		for k, vv := range v {
-			u.Set(k, fmt.Sprintf("%v", vv))
-		}
-		r = u.Encode()
-		return r, nil
 	case map[string]string:
 		u := url.Values{}
 		for k, vv := range v {
@@ -97,7 +105,13 @@ func buildHttpStringParams(params interface{}, bodyLogger io.Writer) (string, er
 		r = fmt.Sprintf("%v", v)
 		return r, nil
 	default:
-		return r, fmt.Errorf("unknown type to build http params")
+		// map[string]interface{}, other maps and structs
+		u, err := enc.encode(params)
+		if err != nil {
+			return r, err
+		}
+		r = u.Encode()
+		return r, nil
 	}
 }
 
@@ -115,6 +129,9 @@ func buildJsonParams(params interface{}, bodyLogger io.Writer) (io.ReadSeeker, e
 	}
 
 	switch v := params.(type) {
+	case *BodyStream:
+		j = "[stream]"
+		return v.newBody(), nil
 	case io.ReadSeeker:
 		j = "[io.ReadSeeker]"
 		return v, nil
@@ -135,12 +152,9 @@ func buildJsonParams(params interface{}, bodyLogger io.Writer) (io.ReadSeeker, e
 		j = bb
 		return bytes.NewReader(bb), nil
 	case io.Reader:
-		p, err := io.ReadAll(v)
-		if err != nil {
-			return nil, err
-		}
-		j = p
-		return bytes.NewReader(p), nil
+		// streamed instead of being read into memory
+		j = "[stream]"
+		return Stream(v, -1).newBody(), nil
 	case []byte:
 		j = v
 		return bytes.NewReader(v), nil
@@ -157,7 +171,7 @@ func buildJsonParams(params interface{}, bodyLogger io.Writer) (io.ReadSeeker, e
 	}
 }
 
-func adjustHttpArgs(url, method string, params interface{}, header map[string]string, bodyLogger io.Writer) (string, string, io.ReadSeeker, map[string]string, error) {
+func adjustHttpArgs(url, method string, params interface{}, header http.Header, option *Options) (string, string, io.ReadSeeker, http.Header, error) {
 	if len(method) == 0 {
 		method = http.MethodGet
 	} else {
@@ -165,50 +179,56 @@ func adjustHttpArgs(url, method string, params interface{}, header map[string]st
 	}
 
 	var paramsReader io.ReadSeeker
+	url, err := addPathParams(url, option)
+	if err != nil {
+		return url, method, paramsReader, header, err
+	}
 
-	switch method {
-	case http.MethodGet, http.MethodHead:
-		p, err := buildHttpStringParams(params, bodyLogger)
+	if option.paramsInQuery(method) {
+		p, err := buildHttpStringParams(params, option.bodyLogger, &option.formEncoder)
 		if err != nil {
 			return url, method, paramsReader, header, err
 		}
-		if len(p) > 0 {
-			deli := '?'
-			if strings.Contains(url, "?") {
-				deli = '&'
-			}
-			url = fmt.Sprintf("%s%c%s", url, deli, p)
-		}
-	default:
-		p, err := buildHttpParams(params, bodyLogger)
+		url = appendQuery(url, p)
+	} else {
+		p, err := buildHttpParams(params, option.bodyLogger, &option.formEncoder)
 		if err != nil {
 			return url, method, paramsReader, header, err
 		}
 
 		paramsReader = p
-		if header == nil {
-			header = map[string]string{headerContentType: mimeURLEncoded}
+		if m, ok := params.(*MultipartParams); ok {
+			// the boundary must be the one used by the body, so it always overrides
+			header = setContentType(header, m.FormDataContentType(), true)
 		} else {
-			ct := http.CanonicalHeaderKey(headerContentType)
-			found := false
-			for k, _ := range header {
-				if http.CanonicalHeaderKey(k) == ct {
-					found = true
-					break
-				}
-			}
-			if !found {
-				header[ct] = mimeURLEncoded
-			}
+			header = setContentType(header, mimeURLEncoded, false)
 		}
 	}
-	return url, method, paramsReader, header, nil
+
+	url, err = addQuery(url, option)
+	return url, method, paramsReader, header, err
+}
+
+func setContentType(header http.Header, contentType string, override bool) http.Header {
+	if header == nil {
+		header = http.Header{}
+	}
+	if override || len(header.Get(headerContentType)) == 0 {
+		header.Set(headerContentType, contentType)
+	}
+	return header
 }
 
-func adjustJsonArgs(method string, params interface{}, header map[string]string, bodyLogger io.Writer) (string, io.ReadSeeker, map[string]string, error) {
-	j, err := buildJsonParams(params, bodyLogger)
+func adjustJsonArgs(url, method string, params interface{}, header http.Header, option *Options) (string, string, io.ReadSeeker, http.Header, error) {
+	j, err := buildJsonParams(params, option.bodyLogger)
 	if err != nil {
-		return method, nil, header, err
+		return url, method, nil, header, err
+	}
+	if url, err = addPathParams(url, option); err != nil {
+		return url, method, nil, header, err
+	}
+	if url, err = addQuery(url, option); err != nil {
+		return url, method, nil, header, err
 	}
 
 	if len(method) == 0 {
@@ -217,21 +237,41 @@ func adjustJsonArgs(method string, params interface{}, header map[string]string,
 		method = strings.ToUpper(method)
 	}
 
-	if header == nil {
-		header = map[string]string{headerContentType: mimeJSON}
-	} else {
-		ct := http.CanonicalHeaderKey(headerContentType)
-		found := false
-		for k, _ := range header {
-			if http.CanonicalHeaderKey(k) == ct {
-				found = true
-				break
-			}
-		}
-		if !found {
-			header[ct] = mimeJSON
-		}
+	header = setContentType(header, mimeJSON, false)
+	return url, method, j, header, nil
+}
+
+// append the params of Query() option to url
+func addQuery(url string, option *Options) (string, error) {
+	if option.query == nil {
+		return url, nil
+	}
+	q, err := buildHttpStringParams(option.query, option.bodyLogger, &option.formEncoder)
+	if err != nil {
+		return url, err
+	}
+	return appendQuery(url, q), nil
+}
+
+// append an encoded query string to url, keeping the existing query string and fragment
+func appendQuery(url, query string) string {
+	if len(query) == 0 {
+		return url
+	}
+
+	var fragment string
+	if pos := strings.IndexByte(url, '#'); pos >= 0 {
+		url, fragment = url[:pos], url[pos:]
+	}
+
+	switch pos := strings.IndexByte(url, '?'); {
+	case pos < 0:
+		url = fmt.Sprintf("%s?%s", url, query)
+	case pos == len(url)-1, url[len(url)-1] == '&':
+		url = fmt.Sprintf("%s%s", url, query)
+	default:
+		url = fmt.Sprintf("%s&%s", url, query)
 	}
-	return method, j, header, nil
+	return fmt.Sprintf("%s%s", url, fragment)
 }
 
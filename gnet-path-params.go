@@ -0,0 +1,88 @@
+package gnet
+
+import (
+	"net/http"
+	"net/url"
+	"context"
+	"strings"
+	"fmt"
+)
+
+type uriTemplateKey struct{}
+
+// expand the placeholders like {id} in the path of a URL or a relative URI,
+// every value is escaped as a single path segment, "." and ".." are rejected as they are
+// dot-segments which would be removed by the path normalization.
+func expandPathParams(rawurl string, params map[string]interface{}) (string, error) {
+	start := 0
+	if pos := strings.Index(rawurl, "://"); pos >= 0 {
+		// skip scheme and host
+		if slash := strings.IndexByte(rawurl[pos+3:], '/'); slash >= 0 {
+			start = pos + 3 + slash
+		} else {
+			return rawurl, nil
+		}
+	}
+	end := len(rawurl)
+	if pos := strings.IndexAny(rawurl[start:], "?#"); pos >= 0 {
+		end = start + pos
+	}
+
+	b := &strings.Builder{}
+	b.WriteString(rawurl[:start])
+	p := rawurl[start:end]
+	for {
+		l := strings.IndexByte(p, '{')
+		if l < 0 {
+			break
+		}
+		r := strings.IndexByte(p[l:], '}')
+		if r < 0 {
+			return rawurl, fmt.Errorf("unclosed path param in %s", rawurl)
+		}
+		r += l
+		name := p[l+1:r]
+		v, ok := params[name]
+		if !ok {
+			return rawurl, fmt.Errorf("path param %s not given", name)
+		}
+		segment := fmt.Sprintf("%v", v)
+		if segment == "." || segment == ".." {
+			return rawurl, fmt.Errorf("path param %s can not be %s", name, segment)
+		}
+		b.WriteString(p[:l])
+		b.WriteString(url.PathEscape(segment))
+		p = p[r+1:]
+	}
+	b.WriteString(p)
+	b.WriteString(rawurl[end:])
+	return b.String(), nil
+}
+
+// expand path params of PathParams() option, and record the template
+func addPathParams(url string, option *Options) (string, error) {
+	if option.pathParams == nil {
+		return url, nil
+	}
+	option.uriTemplate = url
+	return expandPathParams(url, option.pathParams)
+}
+
+func withUriTemplate(req *http.Request, uriTemplate string) *http.Request {
+	if len(uriTemplate) == 0 {
+		return req
+	}
+	return req.WithContext(context.WithValue(req.Context(), uriTemplateKey{}, uriTemplate))
+}
+
+// the URI template given with PathParams(), e.g. "/users/{id}", it can be used as the label of metrics.
+// resp.Request can be used to get the template from a response.
+func UriTemplate(req *http.Request) string {
+	if req == nil {
+		return ""
+	}
+	if t, ok := req.Context().Value(uriTemplateKey{}).(string); ok {
+		return t
+	}
+	return ""
+}
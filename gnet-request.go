@@ -10,6 +10,7 @@ var (
 	getHttpsClient = httpsClientCreator()
 	getHttpsClientWithCertFiles = httpsClientWithCertFilesCreator()
 	getHttpsClientWithCertBlocks = httpsClientWithCertBlocksCreator()
+	getUnixClient = unixClientCreator()
 )
 
 type Request struct {
@@ -29,7 +30,7 @@ func NewHttpsRequest(options ...Option) *Request {
 
 func NewHttpsRequestWithCerts(certPemFile, keyPemFile string, options ...Option) (*Request, error) {
 	option := getOptions(options...)
-	client, err := getHttpsClientWithCertFiles(certPemFile, keyPemFile, option.timeout)
+	client, err := getHttpsClientWithCertFiles(certPemFile, keyPemFile, option.timeout, &option.dial)
 	if err != nil {
 		return nil, err
 	}
@@ -37,6 +38,9 @@ func NewHttpsRequestWithCerts(certPemFile, keyPemFile string, options ...Option)
 }
 
 func newRequest(url string, option *Options) (*Request, error) {
+	if len(option.unixSocket) > 0 {
+		return newHttpRequest(option), nil
+	}
 	if strings.Index(url, "https://") == 0 {
 		if len(option.certPEMBlock) > 0 && len(option.keyPEMBlock) > 0 {
 			return newHttpsRequestWithCerts(option)
@@ -48,17 +52,20 @@ func newRequest(url string, option *Options) (*Request, error) {
 }
 
 func newHttpRequest(option *Options) *Request {
-	client := getHttpClient(option.timeout)
+	if len(option.unixSocket) > 0 {
+		return &Request{client: getUnixClient(option.unixSocket, option.timeout), options: option}
+	}
+	client := getHttpClient(option.timeout, &option.dial)
 	return &Request{client: client, options: option}
 }
 
 func newHttpsRequest(option *Options) *Request {
-	client := getHttpsClient(option.timeout)
+	client := getHttpsClient(option.timeout, &option.dial)
 	return &Request{client: client, options: option}
 }
 
 func newHttpsRequestWithCerts(option *Options) (*Request, error) {
-	client, err := getHttpsClientWithCertBlocks(option.caCert, option.certPEMBlock, option.keyPEMBlock, option.timeout)
+	client, err := getHttpsClientWithCertBlocks(option.caCert, option.certPEMBlock, option.keyPEMBlock, option.timeout, &option.dial)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,232 @@
+package gnet
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"bytes"
+	"sync"
+	"fmt"
+	"io"
+)
+
+// ---- registry of URL schemes ----
+// the URLs of a registered scheme are served by its RoundTripper through Http, JSON, Get, FsCall,
+// ModTime and others. Only "data" is registered by default, "file" must be registered
+// explicitly with FileTransport() and a root dir, so file URLs from outside can't read any local file.
+
+var schemes = struct {
+	sync.RWMutex
+	m map[string]http.RoundTripper
+}{
+	m: map[string]http.RoundTripper{
+		"data": dataTransport{},
+	},
+}
+
+// RegisterScheme makes the URLs of scheme served by transport, e.g. "mem" with HandlerTransport() for tests.
+// transport nil unregisters the scheme. http and https can not be registered.
+func RegisterScheme(scheme string, transport http.RoundTripper) error {
+	scheme = strings.ToLower(scheme)
+	if scheme == "http" || scheme == "https" || !validScheme(scheme) {
+		return fmt.Errorf("scheme %s can not be registered", scheme)
+	}
+	schemes.Lock()
+	defer schemes.Unlock()
+	if transport == nil {
+		delete(schemes.m, scheme)
+	} else {
+		schemes.m[scheme] = transport
+	}
+	return nil
+}
+
+// FileTransport serves file URLs from the dir root, e.g. RegisterScheme("file", FileTransport("/srv/data"))
+// makes "file:///a.txt" read "/srv/data/a.txt". The paths can't go out of root.
+func FileTransport(root string) http.RoundTripper {
+	return http.NewFileTransport(http.Dir(root))
+}
+
+func lookupScheme(scheme string) http.RoundTripper {
+	schemes.RLock()
+	defer schemes.RUnlock()
+	return schemes.m[scheme]
+}
+
+// lower-case scheme of rawurl, "" if no scheme
+func urlScheme(rawurl string) string {
+	pos := strings.IndexByte(rawurl, ':')
+	if pos <= 0 || !validScheme(rawurl[:pos]) {
+		return ""
+	}
+	return strings.ToLower(rawurl[:pos])
+}
+
+// scheme = ALPHA *( ALPHA / DIGIT / "+" / "-" / "." )
+func validScheme(scheme string) bool {
+	if len(scheme) == 0 {
+		return false
+	}
+	for i:=0; i<len(scheme); i++ {
+		c := scheme[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case i > 0 && (c >= '0' && c <= '9' || c == '+' || c == '-' || c == '.'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// rawurl is absolute if it is http/https or of a registered scheme, otherwise it is relative to
+// the base URL of MultiBase(), or a local file of ModTime().
+func isAbsUrl(rawurl string) bool {
+	if isHttpUrl(rawurl) {
+		return true
+	}
+	scheme := urlScheme(rawurl)
+	return len(scheme) > 0 && lookupScheme(scheme) != nil
+}
+
+// the transport of the scheme, which serves the redirects of the same scheme only.
+func schemeTransport(rawurl string) http.RoundTripper {
+	scheme := urlScheme(rawurl)
+	if len(scheme) == 0 || scheme == "http" || scheme == "https" {
+		return nil
+	}
+	if rt := lookupScheme(scheme); rt != nil {
+		return &sameSchemeTransport{scheme: scheme, rt: rt}
+	}
+	return nil
+}
+
+type sameSchemeTransport struct {
+	scheme string
+	rt http.RoundTripper
+}
+
+func (t *sameSchemeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !strings.EqualFold(req.URL.Scheme, t.scheme) {
+		return nil, fmt.Errorf("redirect from %s to %s not allowed", t.scheme, req.URL.Scheme)
+	}
+	return t.rt.RoundTrip(req)
+}
+
+// ---- data: URLs (RFC 2397) ----
+type dataTransport struct{}
+
+func (dataTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return newMemResponse(req, http.StatusMethodNotAllowed, http.Header{}, nil), nil
+	}
+
+	// data:[<mediatype>][;base64],<data>
+	u := *req.URL
+	u.Fragment, u.RawFragment = "", ""
+	v := strings.TrimPrefix(u.String(), u.Scheme + ":")
+	comma := strings.IndexByte(v, ',')
+	if comma < 0 {
+		return nil, fmt.Errorf("invalid data URL")
+	}
+	mediaType, data := v[:comma], v[comma+1:]
+	isBase64 := false
+	if strings.HasSuffix(strings.ToLower(mediaType), ";base64") {
+		isBase64 = true
+		mediaType = mediaType[:len(mediaType) - len(";base64")]
+	}
+	mediaType, err := url.PathUnescape(mediaType)
+	if err != nil || len(mediaType) == 0 {
+		mediaType = "text/plain;charset=US-ASCII"
+	} else if strings.HasPrefix(mediaType, ";") {
+		mediaType = "text/plain" + mediaType
+	}
+
+	content, err := url.PathUnescape(data)
+	if err != nil {
+		return nil, err
+	}
+	body := []byte(content)
+	if isBase64 {
+		if body, err = base64.StdEncoding.DecodeString(content); err != nil {
+			// the padding may be omitted
+			if body, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(content, "=")); err != nil {
+				return nil, err
+			}
+		}
+	}
+	header := http.Header{}
+	header.Set(headerContentType, mediaType)
+	return newMemResponse(req, http.StatusOK, header, body), nil
+}
+
+// ---- http.Handler served in memory ----
+
+// HandlerTransport serves the requests with handler in memory, it can be registered
+// as a scheme, e.g. "mem", for tests.
+func HandlerTransport(handler http.Handler) http.RoundTripper {
+	return &handlerTransport{handler: handler}
+}
+
+type handlerTransport struct {
+	handler http.Handler
+}
+
+func (t *handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := req.Clone(req.Context())
+	r.RequestURI = req.URL.RequestURI()
+	r.RemoteAddr = "memory"
+	if r.Body == nil {
+		r.Body = http.NoBody
+	}
+	w := &memResponseWriter{header: http.Header{}}
+	t.handler.ServeHTTP(w, r)
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return newMemResponse(req, w.status, w.header, w.body.Bytes()), nil
+}
+
+type memResponseWriter struct {
+	header http.Header
+	status int
+	body bytes.Buffer
+}
+
+func (w *memResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *memResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *memResponseWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(b)
+}
+
+func newMemResponse(req *http.Request, status int, header http.Header, body []byte) *http.Response {
+	resp := &http.Response{
+		Status: fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode: status,
+		Proto: "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header: header,
+		ContentLength: int64(len(body)),
+		Request: req,
+	}
+	if req.Method == http.MethodHead {
+		resp.Body = http.NoBody
+	} else {
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return resp
+}
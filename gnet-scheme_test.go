@@ -0,0 +1,101 @@
+package gnet
+
+import (
+	"testing"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"io/fs"
+	"time"
+	"io"
+	"os"
+)
+
+func TestSchemes(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "hello.txt")
+	os.WriteFile(file, []byte("hello file"), 0644)
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	os.Chtimes(file, modTime, modTime)
+
+	// file:// is not registered by default
+	if _, content, _, err := Http("file://" + filepath.ToSlash(file)); err == nil {
+		t.Fatalf("file:// expected to be rejected, got %s\n", content)
+	}
+	if err := RegisterScheme("file", FileTransport(dir)); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer RegisterScheme("file", nil)
+
+	// file:// rooted at dir
+	if status, content, _, err := Http("file:///hello.txt"); err != nil || status != http.StatusOK || string(content) != "hello file" {
+		t.Fatalf("unexpected %d, %s, %v\n", status, content, err)
+	}
+	if status, _, _, err := Http("file:///../" + filepath.Base(dir) + "/hello.txt"); err != nil || status != http.StatusNotFound {
+		t.Fatalf("path out of root expected not found, got %d, %v\n", status, err)
+	}
+	fp := Get("file:///hello.txt")
+	b, err := io.ReadAll(fp)
+	fp.Close()
+	if err != nil || string(b) != "hello file" {
+		t.Fatalf("unexpected %s, %v\n", b, err)
+	}
+	if mt, err := ModTime("file:///hello.txt"); err != nil || !mt.Equal(modTime) {
+		t.Fatalf("unexpected %v, %v\n", mt, err)
+	}
+	if mt, err := ModTime(file); err != nil || !mt.Equal(modTime) {
+		t.Fatalf("unexpected %v, %v\n", mt, err)
+	}
+
+	// data:
+	cases := []struct{
+		url string
+		contentType string
+		content string
+	}{
+		{"data:,Hello%2C%20World%21", "text/plain;charset=US-ASCII", "Hello, World!"},
+		{"data:text/plain;base64,SGVsbG8sIFdvcmxkIQ==", "text/plain", "Hello, World!"},
+		{"data:application/json,{\"a\":1}", "application/json", `{"a":1}`},
+	}
+	for _, c := range cases {
+		status, content, resp, err := Http(c.url)
+		if err != nil || status != http.StatusOK || string(content) != c.content || resp.Header.Get("Content-Type") != c.contentType {
+			t.Fatalf("%s: unexpected %d, %s, %v\n", c.url, status, content, err)
+		}
+	}
+
+	// custom scheme
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hello", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello mem " + r.URL.Query().Get("name"))
+	})
+	mux.Handle("/redirect", http.RedirectHandler("/hello?name=redirected", http.StatusFound))
+	mux.Handle("/escape", http.RedirectHandler("http://127.0.0.1:1/", http.StatusFound))
+	if err := RegisterScheme("mem", HandlerTransport(mux)); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer RegisterScheme("mem", nil)
+
+	if _, content, _, err := Http("mem://test/hello", Params(map[string]string{"name": "gnet"})); err != nil || string(content) != "hello mem gnet" {
+		t.Fatalf("unexpected %s, %v\n", content, err)
+	}
+	if _, content, _, err := Http("mem://test/redirect"); err != nil || string(content) != "hello mem redirected" {
+		t.Fatalf("unexpected %s, %v\n", content, err)
+	}
+	if _, _, _, err := Http("mem://test/escape"); err == nil {
+		t.Fatalf("redirect to another scheme expected to fail\n")
+	}
+	if b, err := fs.ReadFile(NewFS("mem://test"), "hello"); err != nil || string(b) != "hello mem " {
+		t.Fatalf("unexpected %s, %v\n", b, err)
+	}
+	if err := RegisterScheme("https", HandlerTransport(mux)); err == nil {
+		t.Fatalf("https expected not to be registered\n")
+	}
+
+	// a redirect from http to file:// is not followed
+	ts := httptest.NewServer(http.RedirectHandler("file:///hello.txt", http.StatusFound))
+	defer ts.Close()
+	if _, content, _, err := Http(ts.URL); err == nil {
+		t.Fatalf("redirect to file:// expected to fail, got %s\n", content)
+	}
+}
@@ -0,0 +1,246 @@
+package gnet
+
+import (
+	"net/http"
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	defaultSegments = 4
+	minSegmentSize = 64 * 1024
+)
+
+// SegmentedDownload saves the content of url to path by fetching segments byte ranges concurrently.
+// The size and the support of ranges are probed with HEAD, it falls back to Download() if ranges are not
+// supported. If url is relative and MultiBase() is given, the segments are spread across the base URLs.
+// Every segment is retried from where it stopped, also after it is stalled longer than the timeout of
+// WithTimeout(), but a failed segmented download is not resumed by a later call. The options WithProgress(), ExpectChecksum() and MaxRetries() can be used.
+func SegmentedDownload(url, path string, segments int, options ...Option) error {
+	option := getOptions(options...)
+	if segments <= 0 {
+		segments = defaultSegments
+	}
+
+	urls := []string{url}
+	if b := option.multiBase; !isAbsUrl(url) && b != nil {
+		startIdx := b.pick()
+		urls = make([]string, len(b.baseItems))
+		for i := range urls {
+			urls[i] = fmt.Sprintf("%s%s", b.baseItems[(startIdx+i)%len(b.baseItems)].baseUrl, url)
+		}
+	}
+
+	size, validator, header, ok := probeRanges(urls, options)
+	if !ok {
+		return Download(url, path, options...)
+	}
+	if n := int((size + minSegmentSize - 1) / minSegmentSize); n < segments {
+		segments = n
+	}
+	if segments <= 1 {
+		return Download(url, path, options...)
+	}
+
+	retries := defaultDownloadRetries
+	if option.maxRetriesSet {
+		retries = option.maxRetries
+	}
+	ctx := option.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	tmpFile := path + partFileSuffix
+	fp, err := os.OpenFile(tmpFile, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if err = fp.Truncate(size); err != nil {
+		fp.Close()
+		return err
+	}
+
+	d := &segmentedDownloader{
+		ctx: ctx,
+		urls: urls,
+		options: append(options[:len(options):len(options)], WithContext(ctx)),
+		validator: validator,
+		fp: fp,
+		retries: retries,
+		total: size,
+		progress: option.progress,
+	}
+	if d.progress != nil {
+		d.progress(0, size)
+	}
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var fetchErr error
+	segmentSize := size / int64(segments)
+	for i:=0; i<segments; i++ {
+		start, end := int64(i) * segmentSize, int64(i+1) * segmentSize - 1
+		if i == segments - 1 {
+			end = size - 1
+		}
+		wg.Add(1)
+		go func(i int, start, end int64) {
+			defer wg.Done()
+			if err := d.fetch(i, start, end); err != nil {
+				// the first error is kept, and other segments are stopped
+				once.Do(func() {
+					fetchErr = err
+					cancel()
+				})
+			}
+		}(i, start, end)
+	}
+	wg.Wait()
+
+	if err = fp.Close(); fetchErr != nil {
+		err = fetchErr
+	}
+	if err != nil {
+		os.Remove(tmpFile)
+		return err
+	}
+
+	if err = verifyChecksum(tmpFile, option, header); err != nil {
+		os.Remove(tmpFile)
+		return err
+	}
+	return os.Rename(tmpFile, path)
+}
+
+// SegmentedDownload of the uri, the segments are spread across the base URLs.
+func (b *BaseUrl) SegmentedDownload(uri, path string, segments int, options ...Option) error {
+	return SegmentedDownload(uri, path, segments, append(options[:len(options):len(options)], MultiBase(b))...)
+}
+
+// HEAD the urls one by one, ok is false if the size is unknown or ranges are not supported.
+func probeRanges(urls []string, options []Option) (size int64, validator string, header http.Header, ok bool) {
+	for _, url := range urls {
+		option := getOptions(options...)
+		option.method = http.MethodHead
+		option.params = nil
+		status, _, resp, err := http_i(url, option)
+		if err != nil {
+			continue
+		}
+		if status != http.StatusOK {
+			return
+		}
+		if !strings.Contains(strings.ToLower(resp.Header.Get("Accept-Ranges")), "bytes") {
+			return
+		}
+		if size, err = strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64); err != nil || size <= 0 {
+			return
+		}
+		if validator = rangeValidator(resp.Header); len(validator) == 0 {
+			// the segments may come from different versions of the content
+			return
+		}
+		return size, validator, resp.Header, true
+	}
+	return
+}
+
+type segmentedDownloader struct {
+	ctx context.Context
+	urls []string
+	options []Option
+	validator string
+	fp *os.File
+	retries int
+
+	mu sync.Mutex
+	downloaded, total int64
+	progress func(downloaded, total int64)
+}
+
+// fetch bytes [start, end] of segment idx, a failed attempt is resumed from the next base URL.
+func (d *segmentedDownloader) fetch(idx int, start, end int64) (err error) {
+	for i:=0; i<=d.retries; i++ {
+		if i > 0 {
+			select {
+			case <-d.ctx.Done():
+				return d.ctx.Err()
+			case <-time.After(downloadRetryInterval):
+			}
+		}
+		var n int64
+		var retry bool
+		n, retry, err = d.fetchRange(d.urls[(idx+i)%len(d.urls)], start, end)
+		start += n
+		if err == nil || !retry {
+			return
+		}
+	}
+	return
+}
+
+func (d *segmentedDownloader) fetchRange(url string, start, end int64) (n int64, retry bool, err error) {
+	option := getOptions(append(d.options[:len(d.options):len(d.options)],
+		SetHeader("Range", fmt.Sprintf("bytes=%d-%d", start, end)),
+		SetHeader("If-Range", d.validator),
+	)...)
+	option.method = http.MethodGet
+	option.params = nil
+	option.dontReadRespBody = true
+	option.streaming = true
+	option.idleTimeout = true
+
+	status, _, resp, err := http_i(url, option)
+	if err != nil {
+		return 0, d.ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+
+	if status != http.StatusPartialContent {
+		// 200 means the content is changed
+		return 0, status >= http.StatusInternalServerError, fmt.Errorf("failed to download range %d-%d of %s: status %d", start, end, url, status)
+	}
+	if s, _, ok := parseContentRange(resp.Header.Get("Content-Range")); !ok || s != start {
+		return 0, false, fmt.Errorf("unexpected Content-Range %s", resp.Header.Get("Content-Range"))
+	}
+
+	w := &offsetWriter{d: d, w: d.fp, offset: start}
+	n, err = io.CopyN(w, resp.Body, end - start + 1)
+	if err != nil {
+		return n, d.ctx.Err() == nil, err
+	}
+	return n, false, nil
+}
+
+func (d *segmentedDownloader) addProgress(n int64) {
+	if d.progress == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.downloaded += n
+	d.progress(d.downloaded, d.total)
+}
+
+// writes to w at offset and reports the progress
+type offsetWriter struct {
+	d *segmentedDownloader
+	w io.WriterAt
+	offset int64
+}
+
+func (o *offsetWriter) Write(b []byte) (int, error) {
+	n, err := o.w.WriteAt(b, o.offset)
+	o.offset += int64(n)
+	o.d.addProgress(int64(n))
+	return n, err
+}
@@ -0,0 +1,127 @@
+package gnet
+
+import (
+	"testing"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"crypto/md5"
+	"encoding/hex"
+	"bytes"
+	"sync"
+	"time"
+	"fmt"
+	"os"
+)
+
+func TestSegmentedDownload(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 20000)
+	sum := md5.Sum(content)
+
+	var mu sync.Mutex
+	ranges := map[string]int{}
+	newServer := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet {
+				mu.Lock()
+				ranges[name]++
+				mu.Unlock()
+			}
+			w.Header().Set("ETag", `"v1"`)
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+		}))
+	}
+	ts1, ts2 := newServer("ts1"), newServer("ts2")
+	defer ts1.Close()
+	defer ts2.Close()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file")
+	b, _ := NewBaseUrl2(ts1.URL, ts2.URL)
+
+	var downloaded int64
+	err := b.SegmentedDownload("/file", path, 4, ExpectChecksum("md5", hex.EncodeToString(sum[:])), WithProgress(func(n, total int64) {
+		downloaded = n
+	}))
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if got, _ := os.ReadFile(path); !bytes.Equal(got, content) {
+		t.Fatalf("content mismatch\n")
+	}
+	if ranges["ts1"] != 2 || ranges["ts2"] != 2 {
+		t.Fatalf("segments expected to be spread, got %v\n", ranges)
+	}
+	if downloaded != int64(len(content)) {
+		t.Fatalf("unexpected progress %d\n", downloaded)
+	}
+
+	// fall back to a single stream
+	noRange := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") != "" {
+			t.Errorf("unexpected Range %s\n", r.Header.Get("Range"))
+		}
+		w.Write(content)
+	}))
+	defer noRange.Close()
+
+	other := filepath.Join(dir, "other")
+	if err = SegmentedDownload(noRange.URL, other, 4); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if got, _ := os.ReadFile(other); !bytes.Equal(got, content) {
+		t.Fatalf("content mismatch\n")
+	}
+}
+
+// writes the first n bytes and stalls
+type stalledWriter struct {
+	http.ResponseWriter
+	n int
+	stalled chan struct{}
+}
+
+func (w *stalledWriter) Write(b []byte) (int, error) {
+	if len(b) > w.n {
+		w.ResponseWriter.Write(b[:w.n])
+		w.ResponseWriter.(http.Flusher).Flush()
+		<-w.stalled
+		return 0, fmt.Errorf("stalled")
+	}
+	w.n -= len(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func TestSegmentedDownloadStalled(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 20000)
+	stalled := make(chan struct{})
+	var mu sync.Mutex
+	var ranges []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Method == http.MethodGet {
+			mu.Lock()
+			ranges = append(ranges, r.Header.Get("Range"))
+			first := len(ranges) == 1
+			mu.Unlock()
+			if first {
+				w = &stalledWriter{ResponseWriter: w, n: 1000, stalled: stalled}
+			}
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	}))
+	defer ts.Close()
+	defer close(stalled)
+
+	path := filepath.Join(t.TempDir(), "file")
+	if err := SegmentedDownload(ts.URL, path, 2, WithTimeoutDuration(200*time.Millisecond), MaxRetries(1)); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if got, _ := os.ReadFile(path); !bytes.Equal(got, content) {
+		t.Fatalf("content mismatch\n")
+	}
+	// the stalled segment is resumed
+	if len(ranges) != 3 {
+		t.Fatalf("3 ranges expected, got %v\n", ranges)
+	}
+}
@@ -0,0 +1,215 @@
+package gnet
+
+import (
+	"golang.org/x/net/publicsuffix"
+	"net/http/cookiejar"
+	"net/http"
+	"net/url"
+	"encoding/json"
+	"time"
+	"sync"
+	"fmt"
+	"os"
+	"io"
+)
+
+// Session keeps cookies between calls with its own cookie jar,
+// which is not shared with other sessions even if the pooled clients are shared.
+type Session struct {
+	jar *persistentJar
+	options []Option
+	cookieFile string
+}
+
+// create a session, options are used in every call of the session.
+func NewSession(options ...Option) (*Session, error) {
+	jar, err := newPersistentJar()
+	if err != nil {
+		return nil, err
+	}
+	return &Session{jar: jar, options: options}, nil
+}
+
+// create a session whose cookies are loaded from cookieFile if it exists, and saved to it by Save().
+func NewSessionWithCookieFile(cookieFile string, options ...Option) (*Session, error) {
+	s, err := NewSession(options...)
+	if err != nil {
+		return nil, err
+	}
+	s.cookieFile = cookieFile
+	if err = s.jar.load(cookieFile); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return s, nil
+}
+
+// a cookie jar aware of public suffixes, so a site can't set cookies for e.g. ".co.uk".
+func NewCookieJar() (http.CookieJar, error) {
+	return cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+}
+
+func (s *Session) Http(url string, options ...Option) (status int, content []byte, resp *http.Response, err error) {
+	return Http(url, s.withOptions(options)...)
+}
+
+func (s *Session) JSON(url string, options ...Option) (status int, content []byte, resp *http.Response, err error) {
+	return JSON(url, s.withOptions(options)...)
+}
+
+func (s *Session) GetUsingBodyParams(url string, options ...Option) (status int, content []byte, resp *http.Response, err error) {
+	return GetUsingBodyParams(url, s.withOptions(options)...)
+}
+
+func (s *Session) HttpCallJ(url string, res interface{}, options ...Option) (int, error) {
+	return HttpCallJ(url, res, s.withOptions(options)...)
+}
+
+func (s *Session) JSONCallJ(url string, res interface{}, options ...Option) (int, error) {
+	return JSONCallJ(url, res, s.withOptions(options)...)
+}
+
+// the option to use the cookie jar of the session in other calls, e.g. BaseUrl.Http()
+func (s *Session) CookieJar() Option {
+	return WithCookieJar(s.jar)
+}
+
+func (s *Session) Cookies(u *url.URL) []*http.Cookie {
+	return s.jar.Cookies(u)
+}
+
+// save cookies to the cookie file of the session
+func (s *Session) Save() error {
+	if len(s.cookieFile) == 0 {
+		return fmt.Errorf("no cookie file for the session")
+	}
+	return s.jar.save(s.cookieFile)
+}
+
+func (s *Session) withOptions(options []Option) []Option {
+	o := make([]Option, 0, len(s.options) + len(options) + 1)
+	o = append(o, s.options...)
+	o = append(o, WithCookieJar(s.jar))
+	return append(o, options...)
+}
+
+// ---- cookie jar recording cookies to be saved ----
+type savedCookie struct {
+	URL      string    `json:"url"`
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Path     string    `json:"path,omitempty"`
+	Domain   string    `json:"domain,omitempty"`
+	Expires  time.Time `json:"expires,omitempty"`
+	Secure   bool      `json:"secure,omitempty"`
+	HttpOnly bool      `json:"httpOnly,omitempty"`
+	SameSite http.SameSite `json:"sameSite,omitempty"`
+}
+
+type persistentJar struct {
+	http.CookieJar
+	mu sync.Mutex
+	cookies map[string]*savedCookie
+}
+
+func newPersistentJar() (*persistentJar, error) {
+	jar, err := NewCookieJar()
+	if err != nil {
+		return nil, err
+	}
+	return &persistentJar{CookieJar: jar, cookies: map[string]*savedCookie{}}, nil
+}
+
+func (j *persistentJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.CookieJar.SetCookies(u, cookies)
+
+	now := time.Now()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, c := range cookies {
+		domain := c.Domain
+		if len(domain) == 0 {
+			domain = u.Hostname()
+		}
+		key := fmt.Sprintf("%s;%s;%s", domain, c.Path, c.Name)
+		if c.MaxAge < 0 || (!c.Expires.IsZero() && c.Expires.Before(now)) {
+			delete(j.cookies, key)
+			continue
+		}
+		expires := c.Expires
+		if c.MaxAge > 0 {
+			expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+		}
+		j.cookies[key] = &savedCookie{
+			URL: (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}).String(),
+			Name: c.Name,
+			Value: c.Value,
+			Path: c.Path,
+			Domain: c.Domain,
+			Expires: expires,
+			Secure: c.Secure,
+			HttpOnly: c.HttpOnly,
+			SameSite: c.SameSite,
+		}
+	}
+}
+
+func (j *persistentJar) save(cookieFile string) error {
+	now := time.Now()
+	j.mu.Lock()
+	cookies := make([]*savedCookie, 0, len(j.cookies))
+	for _, c := range j.cookies {
+		if !c.Expires.IsZero() && c.Expires.Before(now) {
+			continue
+		}
+		cookies = append(cookies, c)
+	}
+	j.mu.Unlock()
+
+	tmpFile := fmt.Sprintf("%s.tmp", cookieFile)
+	fp, err := os.OpenFile(tmpFile, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(fp)
+	enc.SetIndent("", "  ")
+	if err = enc.Encode(cookies); err != nil {
+		fp.Close()
+		os.Remove(tmpFile)
+		return err
+	}
+	if err = fp.Close(); err != nil {
+		os.Remove(tmpFile)
+		return err
+	}
+	return os.Rename(tmpFile, cookieFile)
+}
+
+func (j *persistentJar) load(cookieFile string) error {
+	fp, err := os.Open(cookieFile)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+
+	var cookies []*savedCookie
+	if err = json.NewDecoder(fp).Decode(&cookies); err != nil && err != io.EOF {
+		return err
+	}
+	for _, c := range cookies {
+		u, err := url.Parse(c.URL)
+		if err != nil {
+			continue
+		}
+		j.SetCookies(u, []*http.Cookie{&http.Cookie{
+			Name: c.Name,
+			Value: c.Value,
+			Path: c.Path,
+			Domain: c.Domain,
+			Expires: c.Expires,
+			Secure: c.Secure,
+			HttpOnly: c.HttpOnly,
+			SameSite: c.SameSite,
+		}})
+	}
+	return nil
+}
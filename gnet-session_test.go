@@ -0,0 +1,49 @@
+package gnet
+
+import (
+	"testing"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"fmt"
+)
+
+func TestSession(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "s1", Path: "/", MaxAge: 3600})
+		default:
+			if c, err := r.Cookie("sid"); err == nil {
+				fmt.Fprintf(w, "%s", c.Value)
+			}
+		}
+	}))
+	defer ts.Close()
+
+	cookieFile := filepath.Join(t.TempDir(), "cookies.json")
+	s, err := NewSessionWithCookieFile(cookieFile)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if _, _, _, err = s.Http(ts.URL + "/login"); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if _, content, _, _ := s.Http(ts.URL + "/me"); string(content) != "s1" {
+		t.Fatalf("cookie expected in session, got %s\n", content)
+	}
+	if _, content, _, _ := Http(ts.URL + "/me"); len(content) != 0 {
+		t.Fatalf("cookie leaked out of session: %s\n", content)
+	}
+	if err = s.Save(); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	s2, err := NewSessionWithCookieFile(cookieFile)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if _, content, _, _ := s2.Http(ts.URL + "/me"); string(content) != "s1" {
+		t.Fatalf("cookie expected in restored session, got %s\n", content)
+	}
+}
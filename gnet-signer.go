@@ -0,0 +1,304 @@
+package gnet
+
+import (
+	"crypto/sha256"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sort"
+	"time"
+	"fmt"
+	"io"
+)
+
+// Signer signs a request, it is called every time the request is sent, after the body
+// is built from the params and the credentials are set. body is rewound after it is read.
+// A body of Stream() can't be read twice, the signers here return ErrBodyNotReplayable for it.
+type Signer interface {
+	Sign(req *http.Request, body io.ReadSeeker) error
+}
+
+type SignerFunc func(req *http.Request, body io.ReadSeeker) error
+
+func (f SignerFunc) Sign(req *http.Request, body io.ReadSeeker) error {
+	return f(req, body)
+}
+
+// ---- AWS Signature Version 4 ----
+type AWSSigner struct {
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+	Region       string
+	Service      string
+	UnsignedPayload bool // "UNSIGNED-PAYLOAD" is used instead of the hash of body, only for s3
+
+	now func() time.Time
+}
+
+const (
+	awsAlgorithm = "AWS4-HMAC-SHA256"
+	awsUnsignedPayload = "UNSIGNED-PAYLOAD"
+	awsTimeFormat = "20060102T150405Z"
+	awsDateFormat = "20060102"
+)
+
+func NewAWSSigner(accessKey, secretKey, region, service string) *AWSSigner {
+	return &AWSSigner{
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		Region: region,
+		Service: service,
+	}
+}
+
+func (s *AWSSigner) Sign(req *http.Request, body io.ReadSeeker) error {
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	t := now().UTC()
+	amzDate, date := t.Format(awsTimeFormat), t.Format(awsDateFormat)
+
+	var payloadHash string
+	if s.UnsignedPayload && s.Service == "s3" {
+		payloadHash = awsUnsignedPayload
+	} else {
+		var err error
+		if payloadHash, err = hashBody(sha256.New(), body); err != nil {
+			return err
+		}
+	}
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if s.Service == "s3" {
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+	if len(s.SessionToken) > 0 {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+
+	canonicalHeaders, signedHeaders := awsCanonicalHeaders(req)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		awsCanonicalURI(req.URL, s.Service != "s3"),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/%s/aws4_request", date, s.Region, s.Service)
+	stringToSign := strings.Join([]string{awsAlgorithm, amzDate, scope, sha256Hex(canonicalRequest)}, "\n")
+
+	key := hmacSHA256([]byte("AWS4" + s.SecretKey), date)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, s.Service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		awsAlgorithm, s.AccessKey, scope, signedHeaders, signature))
+	return nil
+}
+
+// host, content-type, content-md5 and x-amz-* are signed
+func awsCanonicalHeaders(req *http.Request) (canonicalHeaders, signedHeaders string) {
+	host := req.Host
+	if len(host) == 0 {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for k, v := range req.Header {
+		lk := strings.ToLower(k)
+		if lk == "content-type" || lk == "content-md5" || strings.HasPrefix(lk, "x-amz-") {
+			headers[lk] = canonicalHeaderValue(v)
+		}
+	}
+
+	names := make([]string, 0, len(headers))
+	for k, _ := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	b := &strings.Builder{}
+	for _, k := range names {
+		fmt.Fprintf(b, "%s:%s\n", k, headers[k])
+	}
+	return b.String(), strings.Join(names, ";")
+}
+
+func awsCanonicalURI(u *url.URL, doubleEncode bool) string {
+	p := u.Path
+	if len(p) == 0 {
+		return "/"
+	}
+	segments := strings.Split(p, "/")
+	for i, seg := range segments {
+		seg = uriEscape(seg)
+		if doubleEncode {
+			seg = uriEscape(seg)
+		}
+		segments[i] = seg
+	}
+	return strings.Join(segments, "/")
+}
+
+// ---- generic HMAC-SHA256 signer ----
+
+// HMACSigner signs the string joined with "\n" by
+//   METHOD, escaped path, sorted query, timestamp, nonce,
+//   "name:value" of each signed header, hex of SHA-256 of body
+// and sets the signature, key id, timestamp, nonce and the list of signed headers to the headers.
+// Empty header names disable the corresponding headers.
+type HMACSigner struct {
+	KeyId  string
+	Secret []byte
+
+	SignatureHeader     string
+	KeyIdHeader         string
+	TimestampHeader     string
+	NonceHeader         string
+	SignedHeadersHeader string
+	SignedHeaders   []string // names of headers to be signed
+	TimestampLayout string   // unix seconds if empty
+	Base64Signature bool     // signature in base64 instead of hex
+}
+
+func NewHMACSigner(keyId string, secret []byte, signedHeaders ...string) *HMACSigner {
+	return &HMACSigner{
+		KeyId: keyId,
+		Secret: secret,
+		SignatureHeader: "X-Signature",
+		KeyIdHeader: "X-Key-Id",
+		TimestampHeader: "X-Timestamp",
+		NonceHeader: "X-Nonce",
+		SignedHeadersHeader: "X-Signed-Headers",
+		SignedHeaders: signedHeaders,
+	}
+}
+
+func (s *HMACSigner) Sign(req *http.Request, body io.ReadSeeker) error {
+	bodyHash, err := hashBody(sha256.New(), body)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var timestamp string
+	if len(s.TimestampLayout) == 0 {
+		timestamp = strconv.FormatInt(now.Unix(), 10)
+	} else {
+		timestamp = now.Format(s.TimestampLayout)
+	}
+	var nonce string
+	if len(s.NonceHeader) > 0 {
+		b := make([]byte, 16)
+		rand.Read(b)
+		nonce = hex.EncodeToString(b)
+	}
+
+	lines := []string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		timestamp,
+		nonce,
+	}
+	signedHeaders := make([]string, len(s.SignedHeaders))
+	for i, h := range s.SignedHeaders {
+		lh := strings.ToLower(h)
+		var v string
+		if lh == "host" {
+			if v = req.Host; len(v) == 0 {
+				v = req.URL.Host
+			}
+		} else {
+			v = canonicalHeaderValue(req.Header.Values(h))
+		}
+		lines = append(lines, fmt.Sprintf("%s:%s", lh, v))
+		signedHeaders[i] = lh
+	}
+	lines = append(lines, bodyHash)
+
+	mac := hmacSHA256(s.Secret, strings.Join(lines, "\n"))
+	var signature string
+	if s.Base64Signature {
+		signature = base64.StdEncoding.EncodeToString(mac)
+	} else {
+		signature = hex.EncodeToString(mac)
+	}
+
+	setHeader := func(k, v string) {
+		if len(k) > 0 {
+			req.Header.Set(k, v)
+		}
+	}
+	setHeader(s.KeyIdHeader, s.KeyId)
+	setHeader(s.TimestampHeader, timestamp)
+	setHeader(s.NonceHeader, nonce)
+	setHeader(s.SignedHeadersHeader, strings.Join(signedHeaders, ";"))
+	setHeader(s.SignatureHeader, signature)
+	return nil
+}
+
+// ---- helpers ----
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	io.WriteString(h, data)
+	return h.Sum(nil)
+}
+
+func sha256Hex(data string) string {
+	h := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(h[:])
+}
+
+// keys and values are sorted and escaped as RFC 3986
+func canonicalQuery(query url.Values) string {
+	pairs := make([][2]string, 0, len(query))
+	for k, vs := range query {
+		for _, v := range vs {
+			pairs = append(pairs, [2]string{uriEscape(k), uriEscape(v)})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] == pairs[j][0] {
+			return pairs[i][1] < pairs[j][1]
+		}
+		return pairs[i][0] < pairs[j][0]
+	})
+	q := make([]string, len(pairs))
+	for i, p := range pairs {
+		q[i] = fmt.Sprintf("%s=%s", p[0], p[1])
+	}
+	return strings.Join(q, "&")
+}
+
+func canonicalHeaderValue(values []string) string {
+	vs := make([]string, len(values))
+	for i, v := range values {
+		vs[i] = strings.Join(strings.Fields(v), " ")
+	}
+	return strings.Join(vs, ",")
+}
+
+// escape all characters except the unreserved ones of RFC 3986
+func uriEscape(s string) string {
+	b := &strings.Builder{}
+	for i:=0; i<len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
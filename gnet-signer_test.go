@@ -0,0 +1,61 @@
+package gnet
+
+import (
+	"testing"
+	"net/http"
+	"net/http/httptest"
+	"crypto/sha256"
+	"strings"
+	"time"
+	"fmt"
+	"io"
+)
+
+func TestAWSSigner(t *testing.T) {
+	// example of AWS Signature Version 4 documents
+	req, _ := http.NewRequest(http.MethodGet, "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+	signer := NewAWSSigner("AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "us-east-1", "iam")
+	signer.now = func() time.Time {
+		return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	}
+	if err := signer.Sign(req, nil); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, SignedHeaders=content-type;host;x-amz-date, Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7"
+	if auth := req.Header.Get("Authorization"); auth != expected {
+		t.Fatalf("expected %s, got %s\n", expected, auth)
+	}
+}
+
+func TestHMACSigner(t *testing.T) {
+	secret := []byte("secret")
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		lines := []string{r.Method, r.URL.EscapedPath(), canonicalQuery(r.URL.Query()), r.Header.Get("X-Timestamp"), r.Header.Get("X-Nonce")}
+		for _, h := range strings.Split(r.Header.Get("X-Signed-Headers"), ";") {
+			v := r.Header.Get(h)
+			if h == "host" {
+				v = r.Host
+			}
+			lines = append(lines, fmt.Sprintf("%s:%s", h, v))
+		}
+		lines = append(lines, fmt.Sprintf("%x", sha256.Sum256(body)))
+		if fmt.Sprintf("%x", hmacSHA256(secret, strings.Join(lines, "\n"))) != r.Header.Get("X-Signature") || r.Header.Get("X-Key-Id") != "k1" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		fmt.Fprintf(w, "%s", body)
+	}))
+	defer ts.Close()
+
+	signer := NewHMACSigner("k1", secret, "host", "content-type")
+	status, content, _, err := JSON(ts.URL + "/p?b=2&a=1", Params(map[string]int{"a": 1}), WithSigner(signer))
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if status != http.StatusOK || string(content) != "{\"a\":1}\n" {
+		t.Fatalf("unexpected response %d %s\n", status, content)
+	}
+}
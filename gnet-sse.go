@@ -0,0 +1,259 @@
+package gnet
+
+import (
+	"net/http"
+	"context"
+	"strconv"
+	"strings"
+	"bufio"
+	"sync"
+	"time"
+	"fmt"
+	"io"
+)
+
+const (
+	defaultSSERetry = 3 * time.Second
+	mimeEventStream = "text/event-stream"
+)
+
+// an event of text/event-stream
+type Event struct {
+	Id    string
+	Event string
+	Data  string
+	Retry time.Duration // reconnection time given with the event, 0 if not given
+}
+
+// EventSource is a client of Server-Sent Events, it reconnects with Last-Event-ID
+// after the connection is lost. If the url is relative and MultiBase() is given,
+// the next base URL is used to reconnect after a failure.
+type EventSource struct {
+	url string
+	options []Option
+	mu sync.Mutex
+	lastEventId string
+	retry time.Duration
+	baseIdx int
+}
+
+func NewEventSource(url string, options ...Option) *EventSource {
+	return &EventSource{
+		url: url,
+		options: options,
+		retry: defaultSSERetry,
+		baseIdx: -1,
+	}
+}
+
+// id of the last event received, it is sent as Last-Event-ID when reconnecting.
+func (es *EventSource) LastEventId() string {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	return es.lastEventId
+}
+
+// the initial reconnection time, it is replaced by the retry field sent by the server.
+func (es *EventSource) SetRetry(retry time.Duration) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	es.retry = retry
+}
+
+func (es *EventSource) getRetry() time.Duration {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	return es.retry
+}
+
+// set the Last-Event-ID to resume from
+func (es *EventSource) SetLastEventId(id string) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	es.lastEventId = id
+}
+
+// Run calls handler with every event until ctx is done, handler returns an error,
+// or the server responds with a non-retryable status. nil is returned if the server
+// responds with 204 to stop reconnecting.
+func (es *EventSource) Run(ctx context.Context, handler func(*Event) error) error {
+	for {
+		resp, err := es.connect(ctx)
+		if err == nil {
+			err = es.readEvents(resp.Body, handler)
+			resp.Body.Close()
+		}
+		switch e := err.(type) {
+		case *handlerError:
+			return e.err
+		case *fatalError:
+			return e.err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(es.getRetry()):
+		}
+	}
+}
+
+// Events returns a channel of events, it is closed when Run() returns, the result
+// of which can be received from the error channel.
+func (es *EventSource) Events(ctx context.Context) (<-chan *Event, <-chan error) {
+	events := make(chan *Event)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(events)
+		errc <- es.Run(ctx, func(e *Event) error {
+			select {
+			case events <- e:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+	return events, errc
+}
+
+type handlerError struct {
+	err error
+}
+
+func (e *handlerError) Error() string {
+	return e.err.Error()
+}
+
+// the connection must not be retried, err is nil if the server stops the stream with 204.
+type fatalError struct {
+	err error
+}
+
+func (e *fatalError) Error() string {
+	if e.err == nil {
+		return "no content"
+	}
+	return e.err.Error()
+}
+
+func (es *EventSource) connect(ctx context.Context) (*http.Response, error) {
+	options := make([]Option, 0, len(es.options) + 4)
+	options = append(options, es.options...)
+	options = append(options, WithContext(ctx), SetHeader("Accept", mimeEventStream), SetHeader("Cache-Control", "no-cache"))
+	if lastEventId := es.LastEventId(); len(lastEventId) > 0 {
+		options = append(options, SetHeader("Last-Event-ID", lastEventId))
+	}
+	option := getOptions(options...)
+	option.dontReadRespBody = true
+	option.streaming = true
+
+	url := es.url
+	if b := option.multiBase; !isAbsUrl(url) && b != nil {
+		if es.baseIdx < 0 {
+			es.baseIdx = b.pick()
+		} else {
+			// fail over to the next base URL
+			es.baseIdx = (es.baseIdx + 1) % len(b.baseItems)
+		}
+		url = fmt.Sprintf("%s%s", b.baseItems[es.baseIdx].baseUrl, url)
+	}
+
+	req, err := newRequest(url, option)
+	if err != nil {
+		return nil, &fatalError{err}
+	}
+	if len(option.method) == 0 {
+		option.method = http.MethodGet
+	}
+	_, _, resp, err := req.Http(url, option.method, option.params, option.headers)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+	case resp.StatusCode == http.StatusNoContent:
+		resp.Body.Close()
+		return nil, &fatalError{nil}
+	case resp.StatusCode >= http.StatusInternalServerError:
+		resp.Body.Close()
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	default:
+		resp.Body.Close()
+		return nil, &fatalError{fmt.Errorf("status %d", resp.StatusCode)}
+	}
+	if ct := resp.Header.Get(headerContentType); !strings.HasPrefix(ct, mimeEventStream) {
+		resp.Body.Close()
+		return nil, &fatalError{fmt.Errorf("unexpected Content-Type %s", ct)}
+	}
+	return resp, nil
+}
+
+func (es *EventSource) readEvents(body io.Reader, handler func(*Event) error) error {
+	r := bufio.NewReader(body)
+	// the id becomes the last event id only when the event is dispatched
+	id := es.LastEventId()
+	var eventType string
+	var retry time.Duration
+	data := &strings.Builder{}
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			// an incomplete event is discarded
+			return err
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+		if len(line) == 0 {
+			// dispatch the event
+			es.SetLastEventId(id)
+			if data.Len() > 0 {
+				e := &Event{
+					Id: id,
+					Event: eventType,
+					Data: strings.TrimSuffix(data.String(), "\n"),
+					Retry: retry,
+				}
+				if len(e.Event) == 0 {
+					e.Event = "message"
+				}
+				if err := handler(e); err != nil {
+					return &handlerError{err}
+				}
+			}
+			eventType, retry = "", 0
+			data.Reset()
+			continue
+		}
+		if line[0] == ':' {
+			// comment
+			continue
+		}
+
+		field, value := line, ""
+		if pos := strings.IndexByte(line, ':'); pos >= 0 {
+			field, value = line[:pos], strings.TrimPrefix(line[pos+1:], " ")
+		}
+		switch field {
+		case "event":
+			eventType = value
+		case "data":
+			data.WriteString(value)
+			data.WriteByte('\n')
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				id = value
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(value, 10, 64); err == nil {
+				retry = time.Duration(ms) * time.Millisecond
+				es.SetRetry(retry)
+			}
+		}
+	}
+}
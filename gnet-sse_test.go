@@ -0,0 +1,90 @@
+package gnet
+
+import (
+	"testing"
+	"net/http"
+	"net/http/httptest"
+	"context"
+	"time"
+	"fmt"
+)
+
+func TestEventSource(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		switch r.Header.Get("Last-Event-ID") {
+		case "":
+			fmt.Fprintf(w, ": comment\nretry: 10\n\nid: 1\ndata: a\ndata: b\n\nevent: e\nid: 2\ndata: c\n\n")
+		case "2":
+			fmt.Fprintf(w, "id: 3\ndata: d\n\ndata: incomplete")
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	up := httptest.NewServer(handler)
+	defer up.Close()
+
+	b, _ := NewBaseUrl2(up.URL, down.URL)
+	es := NewEventSource("/events", MultiBase(b))
+	es.SetRetry(10*time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var got []string
+	err := es.Run(ctx, func(e *Event) error {
+		got = append(got, fmt.Sprintf("%s:%s:%s", e.Id, e.Event, e.Data))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	expected := "[1:message:a\nb 2:e:c 3:message:d]"
+	if fmt.Sprintf("%v", got) != expected {
+		t.Fatalf("expected %q, got %q\n", expected, fmt.Sprintf("%v", got))
+	}
+	if es.LastEventId() != "3" {
+		t.Fatalf("unexpected last event id %s\n", es.LastEventId())
+	}
+}
+
+func TestEventSourceCutInEvent(t *testing.T) {
+	var lastEventIds []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		lastEventIds = append(lastEventIds, r.Header.Get("Last-Event-ID"))
+		switch r.Header.Get("Last-Event-ID") {
+		case "":
+			// the stream is cut after the id of event 2
+			fmt.Fprintf(w, "id: 1\ndata: a\n\nid: 2\ndata: b\n")
+		case "1":
+			fmt.Fprintf(w, "id: 2\ndata: b\n\n")
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer ts.Close()
+
+	es := NewEventSource(ts.URL)
+	es.SetRetry(10*time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	events, errc := es.Events(ctx)
+	var got []string
+	for e := range events {
+		got = append(got, e.Id + ":" + e.Data)
+		// no race with Run(), checked with -race
+		es.LastEventId()
+		es.SetRetry(10*time.Millisecond)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if fmt.Sprintf("%v", got) != "[1:a 2:b]" || fmt.Sprintf("%q", lastEventIds) != `["" "1" "2"]` {
+		t.Fatalf("unexpected events %v, Last-Event-IDs %q\n", got, lastEventIds)
+	}
+}
@@ -0,0 +1,147 @@
+package gnet
+
+import (
+	"net/http"
+	"sync"
+	"fmt"
+	"io"
+)
+
+// returned when a streamed body has been sent and can't be sent again for retry or fail-over.
+var ErrBodyNotReplayable = fmt.Errorf("the streamed body can not be replayed")
+
+// BodyStream is a request body streamed without buffering, it can be used as Params().
+// The body is sent with chunked transfer encoding if the content length is unknown (< 0).
+type BodyStream struct {
+	open func() (io.ReadCloser, error)
+	contentLength int64
+	replayable bool
+}
+
+// stream the content of r, it can be sent only once. It can't be hashed by signers or digest
+// auth with qop=auth-int either, ErrBodyNotReplayable is returned without reading r.
+func Stream(r io.Reader, contentLength int64) *BodyStream {
+	return &BodyStream{
+		open: func() (io.ReadCloser, error) {
+			if rc, ok := r.(io.ReadCloser); ok {
+				// e.g. io.PipeReader, closing it stops the writer if the request fails
+				return rc, nil
+			}
+			return io.NopCloser(r), nil
+		},
+		contentLength: contentLength,
+	}
+}
+
+// stream the content generated by write through an io.Pipe, write is called every time the body is sent.
+func StreamFunc(write func(w io.Writer) error, contentLength int64) *BodyStream {
+	return &BodyStream{
+		open: func() (io.ReadCloser, error) {
+			pr, pw := io.Pipe()
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				pw.CloseWithError(write(pw))
+			}()
+			return &pipeBody{PipeReader: pr, done: done}, nil
+		},
+		contentLength: contentLength,
+		replayable: true,
+	}
+}
+
+// stream the content of the reader returned by open, which is called every time the body is sent.
+func StreamFactory(open func() (io.ReadCloser, error), contentLength int64) *BodyStream {
+	return &BodyStream{
+		open: open,
+		contentLength: contentLength,
+		replayable: true,
+	}
+}
+
+func (s *BodyStream) newBody() *streamBody {
+	return &streamBody{s: s}
+}
+
+// pipeBody waits for the writer to return after it is closed, so the sources of write,
+// e.g. the readers of multipart, are not used by 2 writers when the body is resent.
+type pipeBody struct {
+	*io.PipeReader
+	done chan struct{}
+}
+
+func (b *pipeBody) Close() error {
+	err := b.PipeReader.Close()
+	<-b.done
+	return err
+}
+
+// streamBody opens the stream at the first Read(), Seek(0, io.SeekStart) makes it ready
+// to be sent again, ErrBodyNotReplayable is returned if it can't.
+type streamBody struct {
+	s *BodyStream
+	mu sync.Mutex
+	rc io.ReadCloser
+	opened bool
+}
+
+func (b *streamBody) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	if b.rc == nil {
+		if b.opened && !b.s.replayable {
+			b.mu.Unlock()
+			return 0, ErrBodyNotReplayable
+		}
+		rc, err := b.s.open()
+		if err != nil {
+			b.mu.Unlock()
+			return 0, err
+		}
+		b.rc, b.opened = rc, true
+	}
+	rc := b.rc
+	b.mu.Unlock()
+
+	return rc.Read(p)
+}
+
+func (b *streamBody) Seek(offset int64, whence int) (int64, error) {
+	if offset != 0 || whence != io.SeekStart {
+		return 0, fmt.Errorf("streamed body can only be rewound to start")
+	}
+	b.Close()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.opened && !b.s.replayable {
+		return 0, ErrBodyNotReplayable
+	}
+	return 0, nil
+}
+
+func (b *streamBody) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.rc == nil {
+		return nil
+	}
+	err := b.rc.Close()
+	b.rc = nil
+	return err
+}
+
+// set the content length and GetBody of req, GetBody is used by net/http to resend the body after redirect.
+func (b *streamBody) setup(req *http.Request) {
+	switch {
+	case b.s.contentLength == 0:
+		req.Body, req.ContentLength = http.NoBody, 0
+	case b.s.contentLength > 0:
+		req.ContentLength = b.s.contentLength
+	}
+	req.GetBody = func() (io.ReadCloser, error) {
+		if !b.s.replayable {
+			return nil, ErrBodyNotReplayable
+		}
+		return b.s.open()
+	}
+}
@@ -0,0 +1,87 @@
+package gnet
+
+import (
+	wr "github.com/mroth/weightedrand"
+	"testing"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"fmt"
+	"io"
+)
+
+func TestStreamBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%d|%v|%s", r.ContentLength, r.TransferEncoding, b)
+	}))
+	defer ts.Close()
+
+	// the first backend drops the connection after reading the body
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	}))
+	defer broken.Close()
+
+	pr, pw := io.Pipe()
+	go func() {
+		io.WriteString(pw, "piped")
+		pw.Close()
+	}()
+
+	cases := []struct{
+		params interface{}
+		expected string
+	}{
+		{pr, "-1|[chunked]|piped"},
+		{Stream(strings.NewReader("sized"), 5), "5|[]|sized"},
+		{StreamFunc(func(w io.Writer) error { _, err := io.WriteString(w, "generated"); return err }, -1), "-1|[chunked]|generated"},
+	}
+	for _, c := range cases {
+		_, content, _, err := Http(ts.URL, M(http.MethodPut), Params(c.params))
+		if err != nil {
+			t.Fatalf("%v\n", err)
+		}
+		if string(content) != c.expected {
+			t.Fatalf("expected %s, got %s\n", c.expected, content)
+		}
+	}
+
+	// the broken backend is always tried first
+	b, _ := NewBaseUrl2(broken.URL, ts.URL)
+	b.chooser, _ = wr.NewChooser(wr.Choice{Item: 0, Weight: 1})
+
+	gen := StreamFunc(func(w io.Writer) error { _, err := io.WriteString(w, "again"); return err }, 5)
+	if _, content, _, err := b.Http("/", M(http.MethodPut), Params(gen)); err != nil || string(content) != "5|[]|again" {
+		t.Fatalf("replayable body expected to fail over, got %s, %v\n", content, err)
+	}
+	if _, _, _, err := b.Http("/", M(http.MethodPut), Params(Stream(strings.NewReader("once"), 4))); err != ErrBodyNotReplayable {
+		t.Fatalf("ErrBodyNotReplayable expected, got %v\n", err)
+	}
+}
+
+func TestStreamBodyHashed(t *testing.T) {
+	var sent int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		sent++
+		io.WriteString(w, string(b))
+	}))
+	defer ts.Close()
+
+	signer := &HMACSigner{Secret: []byte("secret")}
+	r := strings.NewReader("once")
+	if _, _, _, err := Http(ts.URL, M(http.MethodPut), Params(Stream(r, 4)), WithSigner(signer)); err != ErrBodyNotReplayable {
+		t.Fatalf("ErrBodyNotReplayable expected, got %v\n", err)
+	}
+	if r.Len() != 4 || sent != 0 {
+		t.Fatalf("the body should not be consumed\n")
+	}
+
+	gen := StreamFunc(func(w io.Writer) error { _, err := io.WriteString(w, "signed"); return err }, 6)
+	if _, content, _, err := Http(ts.URL, M(http.MethodPut), Params(gen), WithSigner(signer)); err != nil || string(content) != "signed" {
+		t.Fatalf("unexpected %s, %v\n", content, err)
+	}
+}
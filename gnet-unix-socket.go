@@ -0,0 +1,75 @@
+package gnet
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"fmt"
+)
+
+// ---- http+unix:// scheme ----
+// "http+unix://<socket path>:<request path>", e.g. "http+unix:///var/run/docker.sock:/v1.41/containers/json".
+// A base URL "http+unix:///var/run/docker.sock:" can be used as BaseItemT.
+
+const schemeHttpUnix = "http+unix"
+
+func init() {
+	schemes.m[schemeHttpUnix] = unixSchemeTransport{}
+}
+
+// socket path and the escaped request path of a http+unix URL
+func splitUnixUrl(u *url.URL) (socketPath, reqPath string, err error) {
+	p := u.EscapedPath()
+	if len(u.Host) > 0 {
+		// a relative socket path, e.g. http+unix://run/app.sock:/
+		p = u.Host + p
+	}
+	pos := strings.IndexByte(p, ':')
+	if pos < 0 {
+		socketPath, reqPath = p, "/"
+	} else {
+		socketPath, reqPath = p[:pos], p[pos+1:]
+	}
+	if socketPath, err = url.PathUnescape(socketPath); err != nil || len(socketPath) == 0 {
+		return "", "", fmt.Errorf("no socket path in %s", u)
+	}
+	if !strings.HasPrefix(reqPath, "/") {
+		reqPath = "/" + reqPath
+	}
+	return socketPath, reqPath, nil
+}
+
+type unixSchemeTransport struct{}
+
+func (unixSchemeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	socketPath, reqPath, err := splitUnixUrl(req.URL)
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+
+	r := req.Clone(req.Context())
+	if r.URL, err = url.Parse("http://localhost" + reqPath); err != nil {
+		return nil, err
+	}
+	r.URL.RawQuery = req.URL.RawQuery
+	if len(r.Host) == 0 {
+		r.Host = "localhost"
+	}
+
+	resp, err := unixTransport(socketPath).RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+	if loc := resp.Header.Get("Location"); len(loc) > 0 {
+		// a redirect to the same socket is kept in the http+unix scheme
+		if l, e := r.URL.Parse(loc); e == nil && l.Host == r.URL.Host && l.Scheme == "http" {
+			socketUrl := &url.URL{Path: socketPath}
+			resp.Header.Set("Location", fmt.Sprintf("%s://%s:%s", schemeHttpUnix, socketUrl.EscapedPath(), l.RequestURI()))
+		}
+	}
+	resp.Request = req
+	return resp, nil
+}
@@ -0,0 +1,51 @@
+package gnet
+
+import (
+	"testing"
+	"net/http"
+	"path/filepath"
+	"net"
+	"io"
+	"os"
+)
+
+func TestUnixSocket(t *testing.T) {
+	dir, err := os.MkdirTemp("", "gnet")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer os.RemoveAll(dir)
+	socketPath := filepath.Join(dir, "s.sock")
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Skipf("unix socket not supported: %v\n", err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hello", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello " + r.Host + " " + r.URL.Query().Get("name"))
+	})
+	mux.Handle("/redirect", http.RedirectHandler("/hello?name=redirected", http.StatusFound))
+	server := &http.Server{Handler: mux}
+	go server.Serve(l)
+	defer server.Close()
+
+	if _, content, _, err := Http("http://api.local/hello", WithUnixSocket(socketPath), Params(map[string]string{"name": "option"})); err != nil || string(content) != "hello api.local option" {
+		t.Fatalf("unexpected %s, %v\n", content, err)
+	}
+
+	unixUrl := "http+unix://" + socketPath + ":"
+	if _, content, _, err := Http(unixUrl + "/hello?name=scheme"); err != nil || string(content) != "hello localhost scheme" {
+		t.Fatalf("unexpected %s, %v\n", content, err)
+	}
+	if _, content, _, err := Http(unixUrl + "/redirect"); err != nil || string(content) != "hello localhost redirected" {
+		t.Fatalf("unexpected %s, %v\n", content, err)
+	}
+
+	b, err := NewBaseUrl(BaseItem(unixUrl))
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if _, content, _, err := b.Http("/hello", Params(map[string]string{"name": "base"})); err != nil || string(content) != "hello localhost base" {
+		t.Fatalf("unexpected %s, %v\n", content, err)
+	}
+}
@@ -0,0 +1,122 @@
+package gnet
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"io"
+)
+
+// ---- WebDAV multistatus (RFC 4918) ----
+
+const mimeXML = "application/xml; charset=utf-8"
+
+// body of PROPFIND for the properties of DavResource
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<D:propfind xmlns:D="DAV:"><D:prop>
+<D:resourcetype/><D:getcontentlength/><D:getlastmodified/><D:getcontenttype/><D:getetag/><D:displayname/>
+</D:prop></D:propfind>`
+
+type davMultistatus struct {
+	XMLName   xml.Name      `xml:"DAV: multistatus"`
+	Responses []davResponse `xml:"DAV: response"`
+}
+
+type davResponse struct {
+	Hrefs       []string      `xml:"DAV: href"`
+	Status      string        `xml:"DAV: status"`
+	Propstats   []davPropstat `xml:"DAV: propstat"`
+	Description string        `xml:"DAV: responsedescription"`
+}
+
+type davPropstat struct {
+	Prop   davProp `xml:"DAV: prop"`
+	Status string  `xml:"DAV: status"`
+}
+
+type davProp struct {
+	ResourceType  davResourceType `xml:"DAV: resourcetype"`
+	ContentLength string          `xml:"DAV: getcontentlength"`
+	LastModified  string          `xml:"DAV: getlastmodified"`
+	ContentType   string          `xml:"DAV: getcontenttype"`
+	ETag          string          `xml:"DAV: getetag"`
+	DisplayName   string          `xml:"DAV: displayname"`
+}
+
+type davResourceType struct {
+	Collection *struct{} `xml:"DAV: collection"`
+}
+
+// DavResource is a resource in the multistatus response of WebDAV
+type DavResource struct {
+	Href        string
+	Path        string // unescaped path of Href
+	Status      int    // status of the properties, or of the resource if no properties
+	IsDir       bool
+	Size        int64
+	ModTime     time.Time
+	ContentType string
+	ETag        string
+	DisplayName string
+	Description string // responsedescription
+}
+
+func parseMultistatus(body io.Reader) ([]*DavResource, error) {
+	var ms davMultistatus
+	if err := xml.NewDecoder(body).Decode(&ms); err != nil {
+		return nil, err
+	}
+
+	res := make([]*DavResource, 0, len(ms.Responses))
+	for _, r := range ms.Responses {
+		for _, href := range r.Hrefs {
+			dr := &DavResource{
+				Href: href,
+				Path: hrefPath(href),
+				Status: parseDavStatus(r.Status),
+				Description: strings.TrimSpace(r.Description),
+			}
+			for _, ps := range r.Propstats {
+				status := parseDavStatus(ps.Status)
+				if status != http.StatusOK {
+					if dr.Status == 0 {
+						dr.Status = status
+					}
+					continue
+				}
+				dr.Status = status
+				p := &ps.Prop
+				dr.IsDir = p.ResourceType.Collection != nil
+				dr.Size, _ = strconv.ParseInt(strings.TrimSpace(p.ContentLength), 10, 64)
+				dr.ModTime, _ = http.ParseTime(strings.TrimSpace(p.LastModified))
+				dr.ContentType = p.ContentType
+				dr.ETag = p.ETag
+				dr.DisplayName = p.DisplayName
+			}
+			res = append(res, dr)
+		}
+	}
+	return res, nil
+}
+
+// href is an absolute URL or an absolute path
+func hrefPath(href string) string {
+	u, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return href
+	}
+	return u.Path
+}
+
+// "HTTP/1.1 200 OK" => 200
+func parseDavStatus(status string) int {
+	fields := strings.Fields(status)
+	if len(fields) < 2 {
+		return 0
+	}
+	code, _ := strconv.Atoi(fields[1])
+	return code
+}
@@ -0,0 +1,245 @@
+package gnet
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"bytes"
+	"time"
+	"fmt"
+)
+
+// ---- WebDAV client (RFC 4918) ----
+// url of the functions is absolute, or relative with MultiBase() given. Other options,
+// e.g. auth, TLS and headers, are applied as they are to other requests.
+
+// Depth header of PROPFIND, COPY and LOCK
+type DavDepth int
+const (
+	DepthZero DavDepth = iota
+	DepthOne
+	DepthInfinity
+)
+
+func (d DavDepth) String() string {
+	switch d {
+	case DepthZero:
+		return "0"
+	case DepthOne:
+		return "1"
+	default:
+		return "infinity"
+	}
+}
+
+// DavPropfind lists the properties of url, and its members if depth is DepthOne or DepthInfinity.
+func DavPropfind(url string, depth DavDepth, options ...Option) (status int, resources []*DavResource, err error) {
+	option := getOptions(options...)
+	option.params = propfindBody
+	option = cloneOptions(option, SetHeader("Depth", depth.String()), SetHeader(headerContentType, mimeXML))
+
+	status, content, _, err := davCall("PROPFIND", url, "", option)
+	if err != nil || status != http.StatusMultiStatus {
+		return
+	}
+	resources, err = parseMultistatus(bytes.NewReader(content))
+	return
+}
+
+// DavMkcol creates the collection url, 201 is returned if it is created.
+func DavMkcol(url string, options ...Option) (status int, err error) {
+	option := getOptions(options...)
+	option.params = nil
+	status, _, _, err = davCall("MKCOL", url, "", option)
+	return
+}
+
+// DavDelete deletes url, the members failed to be deleted are returned with 207.
+func DavDelete(url string, options ...Option) (status int, failed []*DavResource, err error) {
+	option := getOptions(options...)
+	option.params = nil
+	return davCallMultistatus(http.MethodDelete, url, "", option)
+}
+
+// DavCopy copies src to dst, which is an absolute URL or a reference resolved against the
+// URL of src, with or without MultiBase(). The members failed to be copied are returned with 207.
+func DavCopy(src, dst string, overwrite bool, depth DavDepth, options ...Option) (status int, failed []*DavResource, err error) {
+	option := getOptions(options...)
+	option.params = nil
+	option = cloneOptions(option, SetHeader("Overwrite", overwriteFlag(overwrite)), SetHeader("Depth", depth.String()))
+	return davCallMultistatus("COPY", src, dst, option)
+}
+
+// DavMove moves src to dst, which is an absolute URL or a reference resolved against the
+// URL of src, with or without MultiBase(). The members failed to be moved are returned with 207.
+func DavMove(src, dst string, overwrite bool, options ...Option) (status int, failed []*DavResource, err error) {
+	option := getOptions(options...)
+	option.params = nil
+	option = cloneOptions(option, SetHeader("Overwrite", overwriteFlag(overwrite)), SetHeader("Depth", DepthInfinity.String()))
+	return davCallMultistatus("MOVE", src, dst, option)
+}
+
+// an active lock returned by DavLock()
+type DavLockInfo struct {
+	Token   string
+	Owner   string
+	Depth   string
+	Timeout time.Duration // 0 if the lock is infinite
+}
+
+// DavLock takes an exclusive write lock of url, timeout <= 0 asks for an infinite lock.
+// The token should be sent with DavLockToken() when the locked resource is changed.
+func DavLock(url, owner string, depth DavDepth, timeout time.Duration, options ...Option) (status int, lock *DavLockInfo, err error) {
+	body := &strings.Builder{}
+	body.WriteString(`<?xml version="1.0" encoding="utf-8"?><D:lockinfo xmlns:D="DAV:">`)
+	body.WriteString(`<D:lockscope><D:exclusive/></D:lockscope><D:locktype><D:write/></D:locktype>`)
+	if len(owner) > 0 {
+		body.WriteString(`<D:owner>`)
+		xml.EscapeText(body, []byte(owner))
+		body.WriteString(`</D:owner>`)
+	}
+	body.WriteString(`</D:lockinfo>`)
+
+	option := getOptions(options...)
+	option.params = body.String()
+	option = cloneOptions(option,
+		SetHeader("Depth", depth.String()), SetHeader("Timeout", davTimeout(timeout)), SetHeader(headerContentType, mimeXML),
+	)
+	status, content, resp, err := davCall("LOCK", url, "", option)
+	if err != nil || (status != http.StatusOK && status != http.StatusCreated) {
+		return
+	}
+	lock, err = parseLockDiscovery(content)
+	if err != nil {
+		return
+	}
+	if token := strings.Trim(resp.Header.Get("Lock-Token"), "<>"); len(token) > 0 {
+		lock.Token = token
+	}
+	return
+}
+
+// DavUnlock releases the lock of token, 204 is returned if it is released.
+func DavUnlock(url, token string, options ...Option) (status int, err error) {
+	option := getOptions(options...)
+	option.params = nil
+	option = cloneOptions(option, SetHeader("Lock-Token", "<" + token + ">"))
+	status, _, _, err = davCall("UNLOCK", url, "", option)
+	return
+}
+
+// submit the lock token with the If header to change the locked resource
+func DavLockToken(token string) Option {
+	return SetHeader("If", "(<" + token + ">)")
+}
+
+func davCallMultistatus(method, url, dst string, option *Options) (status int, failed []*DavResource, err error) {
+	status, content, _, err := davCall(method, url, dst, option)
+	if err != nil || status != http.StatusMultiStatus {
+		return
+	}
+	failed, err = parseMultistatus(bytes.NewReader(content))
+	return
+}
+
+// the base URLs are tried one by one if url is relative, see resolveDestination() for dst.
+func davCall(method, url, dst string, option *Options) (status int, content []byte, resp *http.Response, err error) {
+	option.method = method
+	option.jsonCall = false
+	option.dontReadRespBody = false
+
+	b := option.multiBase
+	if isAbsUrl(url) || b == nil {
+		return davCallUrl(url, dst, option)
+	}
+	b.eachBase(func(baseUrl string) bool {
+		status, content, resp, err = davCallUrl(baseUrl + url, dst, option)
+		return err != nil
+	})
+	return
+}
+
+func davCallUrl(url, dst string, option *Options) (status int, content []byte, resp *http.Response, err error) {
+	if len(dst) > 0 {
+		if dst, err = resolveDestination(url, dst); err != nil {
+			return
+		}
+		option = cloneOptions(option, SetHeader("Destination", dst))
+	}
+	return http_i(url, option)
+}
+
+// dst is an absolute URL, or a reference resolved against the URL of src as RFC 3986,
+// e.g. "c.txt" against "http://h/dav/a/b.txt" is "http://h/dav/a/c.txt", "/dav/c.txt" is "http://h/dav/c.txt".
+func resolveDestination(src, dst string) (string, error) {
+	if isAbsUrl(dst) {
+		return dst, nil
+	}
+	s, err := url.Parse(src)
+	if err != nil {
+		return "", err
+	}
+	d, err := url.Parse(dst)
+	if err != nil {
+		return "", err
+	}
+	return s.ResolveReference(d).String(), nil
+}
+
+func overwriteFlag(overwrite bool) string {
+	if overwrite {
+		return "T"
+	}
+	return "F"
+}
+
+func davTimeout(timeout time.Duration) string {
+	if timeout <= 0 {
+		return "Infinite"
+	}
+	return fmt.Sprintf("Second-%d", int64(timeout / time.Second))
+}
+
+// ---- lockdiscovery of LOCK response ----
+
+type davLockDiscovery struct {
+	XMLName       xml.Name `xml:"DAV: prop"`
+	LockDiscovery struct {
+		ActiveLocks []davActiveLock `xml:"DAV: activelock"`
+	} `xml:"DAV: lockdiscovery"`
+}
+
+type davActiveLock struct {
+	Depth     string `xml:"DAV: depth"`
+	Timeout   string `xml:"DAV: timeout"`
+	LockToken struct {
+		Href string `xml:"DAV: href"`
+	} `xml:"DAV: locktoken"`
+	Owner     struct {
+		InnerXML string `xml:",innerxml"`
+	} `xml:"DAV: owner"`
+}
+
+func parseLockDiscovery(content []byte) (*DavLockInfo, error) {
+	var ld davLockDiscovery
+	if err := xml.Unmarshal(content, &ld); err != nil {
+		return nil, err
+	}
+	if len(ld.LockDiscovery.ActiveLocks) == 0 {
+		return nil, fmt.Errorf("no active lock in response")
+	}
+	al := &ld.LockDiscovery.ActiveLocks[0]
+	lock := &DavLockInfo{
+		Token: strings.TrimSpace(al.LockToken.Href),
+		Owner: strings.TrimSpace(al.Owner.InnerXML),
+		Depth: strings.TrimSpace(al.Depth),
+	}
+	if t := strings.TrimSpace(al.Timeout); strings.HasPrefix(t, "Second-") {
+		if secs, err := strconv.ParseInt(t[len("Second-"):], 10, 64); err == nil {
+			lock.Timeout = time.Duration(secs) * time.Second
+		}
+	}
+	return lock, nil
+}
@@ -0,0 +1,95 @@
+package gnet
+
+import (
+	"testing"
+	"net/http"
+	"net/http/httptest"
+	"golang.org/x/net/webdav"
+	"sort"
+	"strings"
+	"time"
+)
+
+func TestWebDAV(t *testing.T) {
+	ts := httptest.NewServer(&webdav.Handler{
+		Prefix: "/dav",
+		FileSystem: webdav.NewMemFS(),
+		LockSystem: webdav.NewMemLS(),
+	})
+	defer ts.Close()
+	base := ts.URL + "/dav"
+
+	if status, err := DavMkcol(base + "/dir", BasicAuth("u", "p")); err != nil || status != http.StatusCreated {
+		t.Fatalf("MKCOL: %d, %v\n", status, err)
+	}
+	if status, _, _, err := Http(base + "/dir/a.txt", M(http.MethodPut), Params("hello")); err != nil || status != http.StatusCreated {
+		t.Fatalf("PUT: %d, %v\n", status, err)
+	}
+
+	// Destination relative to the source URL with BaseUrl
+	b, _ := NewBaseUrl2(base)
+	if status, failed, err := DavCopy("/dir/a.txt", "b.txt", false, DepthInfinity, MultiBase(b)); err != nil || status != http.StatusCreated || len(failed) > 0 {
+		t.Fatalf("COPY: %d, %v, %v\n", status, failed, err)
+	}
+	if status, _, _ := DavCopy(base + "/dir/a.txt", "b.txt", false, DepthZero); status != http.StatusPreconditionFailed {
+		t.Fatalf("COPY without overwrite: %d\n", status)
+	}
+	if status, _, err := DavMove(base + "/dir/b.txt", "/dav/dir/c.txt", true); err != nil || status != http.StatusCreated {
+		t.Fatalf("MOVE: %d, %v\n", status, err)
+	}
+
+	status, resources, err := DavPropfind(base + "/dir/", DepthOne)
+	if err != nil || status != http.StatusMultiStatus {
+		t.Fatalf("PROPFIND: %d, %v\n", status, err)
+	}
+	var names []string
+	for _, r := range resources {
+		names = append(names, r.Path)
+		if r.Path == "/dav/dir/a.txt" && (r.Size != 5 || r.IsDir || r.ModTime.IsZero()) {
+			t.Fatalf("unexpected resource %#v\n", r)
+		}
+	}
+	sort.Strings(names)
+	if strings.Join(names, ",") != "/dav/dir/,/dav/dir/a.txt,/dav/dir/c.txt" {
+		t.Fatalf("unexpected resources %v\n", names)
+	}
+
+	// LOCK and UNLOCK
+	status, lock, err := DavLock(base + "/dir/a.txt", "tester", DepthZero, time.Hour)
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("LOCK: %d, %v\n", status, err)
+	}
+	if len(lock.Token) == 0 || lock.Timeout != time.Hour || !strings.Contains(lock.Owner, "tester") {
+		t.Fatalf("unexpected lock %#v\n", lock)
+	}
+	if status, _, _, _ := Http(base + "/dir/a.txt", M(http.MethodPut), Params("changed")); status != http.StatusLocked {
+		t.Fatalf("423 expected, got %d\n", status)
+	}
+	if status, _, _, _ := Http(base + "/dir/a.txt", M(http.MethodPut), Params("changed"), DavLockToken(lock.Token)); status >= 300 {
+		t.Fatalf("PUT with lock token: %d\n", status)
+	}
+	if status, err := DavUnlock(base + "/dir/a.txt", lock.Token); err != nil || status != http.StatusNoContent {
+		t.Fatalf("UNLOCK: %d, %v\n", status, err)
+	}
+
+	if status, failed, err := DavDelete(base + "/dir/"); err != nil || status != http.StatusNoContent || len(failed) > 0 {
+		t.Fatalf("DELETE: %d, %v, %v\n", status, failed, err)
+	}
+	if status, _, _ := DavPropfind(base + "/dir/", DepthZero); status != http.StatusNotFound {
+		t.Fatalf("404 expected, got %d\n", status)
+	}
+}
+
+func TestResolveDestination(t *testing.T) {
+	cases := [][3]string{
+		{"http://h/dav/a/b", "c", "http://h/dav/a/c"},
+		{"http://h/dav/a/b", "../c", "http://h/dav/c"},
+		{"http://h/dav/a/b", "/c", "http://h/c"},
+		{"http://h/dav/a/b", "http://g/c", "http://g/c"},
+	}
+	for _, c := range cases {
+		if d, err := resolveDestination(c[0], c[1]); err != nil || d != c[2] {
+			t.Fatalf("expected %s, got %s, %v\n", c[2], d, err)
+		}
+	}
+}
@@ -4,7 +4,9 @@ package gnet
 
 import (
 	"net/http"
+	"context"
 	"strings"
+	"sync/atomic"
 	"time"
 	"fmt"
 	"os"
@@ -26,7 +28,7 @@ func JSON(url string, options ...Option) (status int, content []byte, resp *http
 
 func GetUsingBodyParams(url string, options ...Option) (status int, content []byte, resp *http.Response, err error) {
 	option := getOptions(options...)
-	if !isHttpUrl(url) && option.multiBase != nil {
+	if !isAbsUrl(url) && option.multiBase != nil {
 		return option.multiBase.getWithBody(url, option)
 	}
 	var req *Request
@@ -62,8 +64,9 @@ func GetLastModified(resp *http.Response) (time.Time, error) {
 	return time.Time{}, fmt.Errorf("no response header Last-Modified")
 }
 
+// modification time of a URL, or a local file if rawurl is not absolute.
 func ModTime(rawurl string) (modTim time.Time, err error) {
-	if isHttpUrl(rawurl) {
+	if isAbsUrl(rawurl) {
 		option := getOptions()
 		var req *Request
 		if req, err = newRequest(rawurl, option); err != nil {
@@ -87,27 +90,47 @@ func ModTime(rawurl string) (modTim time.Time, err error) {
 
 func (g *Request) Http(url, method string, params interface{}, header map[string]string) (status int, content []byte, resp *http.Response, err error) {
 	var paramsReader io.ReadSeeker
-	if url, method, paramsReader, header, err = adjustHttpArgs(url, method, params, header, g.options.bodyLogger); err != nil {
+	var h http.Header
+	if url, method, paramsReader, h, err = adjustHttpArgs(url, method, params, g.options.mergeHeader(header), g.options); err != nil {
 		return
 	}
-	return g.run(url, method, paramsReader, header)
+	return g.run(url, method, paramsReader, h)
 }
 
 func (g *Request) JSON(url, method string, params interface{}, header map[string]string) (status int, content []byte, resp *http.Response, err error) {
 	var paramsReader io.ReadSeeker
-	if method, paramsReader, header, err = adjustJsonArgs(method, params, header, g.options.bodyLogger); err != nil {
+	var h http.Header
+	if url, method, paramsReader, h, err = adjustJsonArgs(url, method, params, g.options.mergeHeader(header), g.options); err != nil {
 		return
 	}
-	return g.run(url, method, paramsReader, header)
+	return g.run(url, method, paramsReader, h)
 }
 
 func (g *Request) GetUsingBodyParams(url string, params interface{}, header map[string]string) (status int, content []byte, resp *http.Response, err error) {
 	var paramsReader io.ReadSeeker
+	var h http.Header
 	// using http.MethodPost to make a trick
-	if _, _, paramsReader, header, err = adjustHttpArgs(url, http.MethodPost, params, header, g.options.bodyLogger); err != nil {
+	if url, _, paramsReader, h, err = adjustHttpArgs(url, http.MethodPost, params, g.options.mergeHeader(header), g.options); err != nil {
 		return
 	}
-	return g.run(url, http.MethodGet, paramsReader, header)
+	return g.run(url, http.MethodGet, paramsReader, h)
+}
+
+// method must be a token of RFC 7230, e.g. GET, OPTIONS, PROPFIND
+func validMethod(method string) bool {
+	if len(method) == 0 {
+		return false
+	}
+	for i:=0; i<len(method); i++ {
+		c := method[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0:
+		default:
+			return false
+		}
+	}
+	return true
 }
 
 func isHttpUrl(rawurl string) bool {
@@ -123,7 +146,7 @@ func http_i(url string, option *Options) (status int, content []byte, resp *http
 		option.method = http.MethodGet
 	}
 
-	if !isHttpUrl(url) && option.multiBase != nil {
+	if !isAbsUrl(url) && option.multiBase != nil {
 		return option.multiBase.httpCall(url, option)
 	}
 	var req *Request
@@ -137,7 +160,7 @@ func json_i(url string, option *Options) (status int, content []byte, resp *http
 	if len(option.method) == 0 {
 		option.method = http.MethodPost
 	}
-	if !isHttpUrl(url) && option.multiBase != nil {
+	if !isAbsUrl(url) && option.multiBase != nil {
 		return option.multiBase.jsonCall(url, option)
 	}
 	var req *Request
@@ -147,38 +170,56 @@ func json_i(url string, option *Options) (status int, content []byte, resp *http
 	return req.JSON(url, option.method, option.params, option.headers)
 }
 
-func (g *Request) run(url, method string, params io.Reader, header map[string]string) (int, []byte, *http.Response, error) {
-	var req *http.Request
-	var err error
-	switch method {
-	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodPatch:
-		if req, err = http.NewRequest(method, url, params); err != nil {
-			return http.StatusBadRequest, nil, nil, err
-		}
-	default:
+func (g *Request) run(url, method string, params io.ReadSeeker, header http.Header) (int, []byte, *http.Response, error) {
+	if !validMethod(method) {
 		return http.StatusMethodNotAllowed, nil, nil, fmt.Errorf("method %s not supported", method)
 	}
 
-	if len(header) > 0 {
-		for k, v := range header {
-			req.Header.Set(k, v)
-		}
-	}
-	if g.options.basicAuth {
-		req.SetBasicAuth(g.options.baUser, g.options.baPasswd)
-	}
-
+	// the pooled client is shared, so a copy is made to set the fields of this request
+	client := *g.client
 	if g.options.dontCheckRedirect {
-		g.client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
 			return http.ErrUseLastResponse
 		}
 	} else {
-		g.client.CheckRedirect = nil
+		client.CheckRedirect = nil
+	}
+	client.Jar = g.options.cookieJar
+	if rt := schemeTransport(url); rt != nil {
+		client.Transport = rt
+	} else if len(g.options.headerOrder) > 0 {
+		if rt = orderedTransport(client.Transport, g.options.headerOrder); rt != nil {
+			client.Transport = rt
+		}
+	}
+	ctx := g.options.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var idle *idleTimer
+	if g.options.streaming {
+		// the body is read as long as the stream lasts, it can be stopped with WithContext()
+		client.Timeout = 0
+		if g.options.idleTimeout {
+			ctx, idle = newIdleTimer(ctx, g.options.timeout)
+		}
+	} else if g.options.cache != nil {
+		rt := client.Transport
+		if rt == nil {
+			rt = http.DefaultTransport
+		}
+		client.Transport = &cacheTransport{cache: g.options.cache, rt: rt, signed: g.options.signer != nil, timeout: g.options.timeout}
 	}
 
-	resp, err := g.client.Do(req)
+	status, resp, err := g.do(&client, ctx, url, method, params, header)
+	if idle != nil {
+		if err != nil {
+			return status, nil, nil, idle.stop(err)
+		}
+		resp.Body = idle.body(resp.Body)
+	}
 	if err != nil {
-		return http.StatusInternalServerError, nil, nil, err
+		return status, nil, nil, err
 	}
 
 	if g.options.dontReadRespBody {
@@ -196,3 +237,133 @@ func (g *Request) run(url, method string, params io.Reader, header map[string]st
 		return resp.StatusCode, body, resp, nil
 	}
 }
+
+// send the request, it will be resent once if the authenticator can retry after 401.
+func (g *Request) do(client *http.Client, ctx context.Context, url, method string, params io.ReadSeeker, header http.Header) (int, *http.Response, error) {
+	auth := g.options.newAuthenticator()
+	for retried := false; ; retried = true {
+		req, err := g.newHttpRequest(ctx, url, method, params, header)
+		if err != nil {
+			return http.StatusBadRequest, nil, err
+		}
+		if auth != nil {
+			if err = auth.authorize(req, params); err != nil {
+				return http.StatusUnauthorized, nil, err
+			}
+		}
+		if g.options.signer != nil {
+			if err = g.options.signer.Sign(req, params); err != nil {
+				return http.StatusBadRequest, nil, err
+			}
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return http.StatusInternalServerError, nil, err
+		}
+		if retried || auth == nil || resp.StatusCode != http.StatusUnauthorized || !auth.retry(resp) {
+			return resp.StatusCode, resp, nil
+		}
+		if params != nil {
+			if _, err = params.Seek(0, io.SeekStart); err != nil {
+				// the body can't be resent
+				return resp.StatusCode, resp, nil
+			}
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+}
+
+func (g *Request) newHttpRequest(ctx context.Context, url, method string, params io.ReadSeeker, header http.Header) (*http.Request, error) {
+	var body io.Reader
+	if params != nil {
+		body = params
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req = withUriTemplate(req, g.options.uriTemplate)
+	if sb, ok := params.(*streamBody); ok {
+		sb.setup(req)
+	}
+
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if host := header.Get("Host"); len(host) > 0 {
+		// Host in header is ignored by net/http
+		req.Host = host
+		req.Header.Del("Host")
+	}
+	if len(g.options.host) > 0 {
+		req.Host = g.options.host
+	}
+	if g.options.basicAuth {
+		req.SetBasicAuth(g.options.baUser, g.options.baPasswd)
+	} else if len(g.options.credentialProviders) > 0 {
+		// the host of every base URL is looked up independently
+		if user, passwd, ok := lookupCredentials(g.options.credentialProviders, req.URL.Host); ok {
+			req.SetBasicAuth(user, passwd)
+		}
+	}
+	return req, nil
+}
+
+// ---- idle timeout of streamed bodies ----
+// the timeout is applied to waiting for the response headers and every Read of the body,
+// the request is canceled if it is exceeded.
+type idleTimer struct {
+	timeout time.Duration
+	timer *time.Timer
+	cancel context.CancelFunc
+	timedOut int32
+}
+
+func newIdleTimer(ctx context.Context, timeout time.Duration) (context.Context, *idleTimer) {
+	ctx, cancel := context.WithCancel(ctx)
+	it := &idleTimer{timeout: timeout, cancel: cancel}
+	it.timer = time.AfterFunc(timeout, func() {
+		atomic.StoreInt32(&it.timedOut, 1)
+		cancel()
+	})
+	return ctx, it
+}
+
+// stop the timer and the request, err is replaced if it is caused by the timeout
+func (it *idleTimer) stop(err error) error {
+	it.timer.Stop()
+	it.cancel()
+	if err != nil && atomic.LoadInt32(&it.timedOut) == 1 {
+		return fmt.Errorf("no response in %v: %w", it.timeout, err)
+	}
+	return err
+}
+
+// the timer is stopped once the headers are received, and restarted by every Read
+func (it *idleTimer) body(rc io.ReadCloser) io.ReadCloser {
+	it.timer.Stop()
+	return &idleTimeoutBody{rc: rc, it: it}
+}
+
+type idleTimeoutBody struct {
+	rc io.ReadCloser
+	it *idleTimer
+}
+
+func (b *idleTimeoutBody) Read(p []byte) (int, error) {
+	b.it.timer.Reset(b.it.timeout)
+	n, err := b.rc.Read(p)
+	b.it.timer.Stop()
+	if err != nil && err != io.EOF && atomic.LoadInt32(&b.it.timedOut) == 1 {
+		err = fmt.Errorf("no data received in %v: %w", b.it.timeout, err)
+	}
+	return n, err
+}
+
+func (b *idleTimeoutBody) Close() error {
+	err := b.rc.Close()
+	b.it.stop(nil)
+	return err
+}
@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"testing"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"io"
 	"os"
+	"time"
 )
 
 var (
@@ -96,7 +98,7 @@ func Test_DontReadBody(t *testing.T) {
 
 func Test_httpBuildParmas(t *testing.T) {
 	s := strings.NewReader(`{"a":"b","c":"d"}`)
-	if _, err := buildHttpParams(s, os.Stderr); err != nil {
+	if _, err := buildHttpParams(s, os.Stderr, nil); err != nil {
 		fmt.Printf("----failed to buildHttpParams: %v\n", err)
 	} else {
 		fmt.Printf("----buildHttpParmas ok\n")
@@ -110,3 +112,230 @@ func Test_dontRedirect(t *testing.T) {
 func Test_redirect(t *testing.T) {
 	print_result(Http("https://httpbin.org/absolute-redirect/2"))
 }
+
+func Test_Multipart(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1<<20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fp, fh, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer fp.Close()
+		b, _ := io.ReadAll(fp)
+		fmt.Fprintf(w, "%s|%s|%s|%s", r.FormValue("a"), fh.Filename, fh.Header.Get("Content-Type"), b)
+	}))
+	defer ts.Close()
+
+	params := Multipart(
+		FormField("a", "b"),
+		FormReader("file", "x.txt", strings.NewReader("file content")),
+	)
+	status, content, _, err := Http(ts.URL, M(http.MethodPost), Params(params))
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if status != http.StatusOK || string(content) != "b|x.txt|text/plain; charset=utf-8|file content" {
+		t.Fatalf("unexpected response: %d %s\n", status, content)
+	}
+}
+
+func Test_MultipartRedirect(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/307":
+			http.Redirect(w, r, "/upload", http.StatusTemporaryRedirect)
+			return
+		case "/308":
+			http.Redirect(w, r, "/upload", http.StatusPermanentRedirect)
+			return
+		}
+		fp, fh, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer fp.Close()
+		b, _ := io.ReadAll(fp)
+		fmt.Fprintf(w, "%s|%s|%s", r.FormValue("a"), fh.Filename, b)
+	}))
+	defer ts.Close()
+
+	params := Multipart(
+		FormField("a", "b"),
+		FormReader("file", "x.txt", strings.NewReader("file content")),
+	)
+	// the body is resent to the location by net/http with GetBody
+	for _, path := range []string{"/307", "/308"} {
+		status, content, _, err := Http(ts.URL + path, M(http.MethodPost), Params(params))
+		if err != nil || status != http.StatusOK || string(content) != "b|x.txt|file content" {
+			t.Fatalf("unexpected response of %s: %d %s, %v\n", path, status, content, err)
+		}
+	}
+}
+
+func Test_formEncoder(t *testing.T) {
+	type addr struct {
+		City string `form:"city"`
+		Zip  string `form:"zip,omitempty"`
+	}
+	type user struct {
+		Name  string            `form:"name"`
+		Tags  []string          `form:"tags"`
+		Addr  addr              `form:"addr"`
+		Born  time.Time         `form:"born"`
+		Extra map[string]int    `form:"extra,omitempty"`
+		Skip  string            `form:"-"`
+	}
+	u := &user{Name: "x", Tags: []string{"a", "b"}, Addr: addr{City: "c"}, Born: time.Date(2000, 1, 2, 3, 4, 5, 0, time.UTC), Skip: "s"}
+
+	cases := []struct{
+		enc *formEncoder
+		params interface{}
+		expected string
+	}{
+		{nil, u, "addr%5Bcity%5D=c&born=2000-01-02T03%3A04%3A05Z&name=x&tags=a&tags=b"},
+		{&formEncoder{arrayFormat: ArrayBrackets}, map[string]interface{}{"a": []int{1, 2}}, "a%5B%5D=1&a%5B%5D=2"},
+		{&formEncoder{arrayFormat: ArrayIndices}, map[string]interface{}{"a": []int{1, 2}}, "a%5B0%5D=1&a%5B1%5D=2"},
+		{&formEncoder{arrayFormat: ArrayComma}, map[string]interface{}{"a": []int{1, 2}}, "a=1%2C2"},
+		{nil, map[string]interface{}{"m": map[string]interface{}{"k": "v"}}, "m%5Bk%5D=v"},
+	}
+	for _, c := range cases {
+		r, err := buildHttpStringParams(c.params, nil, c.enc)
+		if err != nil {
+			t.Fatalf("%v\n", err)
+		}
+		if r != c.expected {
+			t.Fatalf("expected %s, got %s\n", c.expected, r)
+		}
+	}
+
+	// a map key which is not scalar can't be encoded
+	if _, err := buildHttpStringParams(map[string]interface{}{"m": map[addr]int{{City: "c"}: 1}}, nil, nil); err == nil {
+		t.Fatalf("error expected for struct map key\n")
+	}
+}
+
+func Test_Query(t *testing.T) {
+	cases := [][3]string{
+		{"http://a/b", "x=1", "http://a/b?x=1"},
+		{"http://a/b?", "x=1", "http://a/b?x=1"},
+		{"http://a/b?y=2", "x=1", "http://a/b?y=2&x=1"},
+		{"http://a/b?y=2#frag", "x=1", "http://a/b?y=2&x=1#frag"},
+		{"/b#frag", "x=1", "/b?x=1#frag"},
+	}
+	for _, c := range cases {
+		if u := appendQuery(c[0], c[1]); u != c[2] {
+			t.Fatalf("expected %s, got %s\n", c[2], u)
+		}
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s|%s", r.URL.RawQuery, b)
+	}))
+	defer ts.Close()
+
+	_, content, _, err := JSON(ts.URL + "/p?a=1", Params(map[string]int{"b": 2}), Query(map[string]string{"c": "3"}))
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if string(content) != "a=1&c=3|{\"b\":2}\n" {
+		t.Fatalf("unexpected response: %s\n", content)
+	}
+}
+
+func Test_PathParams(t *testing.T) {
+	pathParams := map[string]interface{}{"id": "a/b?c", "oid": 2}
+	cases := [][2]string{
+		{"http://h/users/{id}/orders/{oid}?x={id}", "http://h/users/a%2Fb%3Fc/orders/2?x={id}"},
+		{"/users/{id}/orders/{oid}#{oid}", "/users/a%2Fb%3Fc/orders/2#{oid}"},
+		{"http://h", "http://h"},
+	}
+	for _, c := range cases {
+		u, err := expandPathParams(c[0], pathParams)
+		if err != nil {
+			t.Fatalf("%v\n", err)
+		}
+		if u != c[1] {
+			t.Fatalf("expected %s, got %s\n", c[1], u)
+		}
+	}
+	if _, err := expandPathParams("/users/{uid}", pathParams); err == nil {
+		t.Fatalf("error expected for missing path param\n")
+	}
+	for _, dots := range []string{".", ".."} {
+		if _, err := expandPathParams("/users/{id}/orders", map[string]interface{}{"id": dots}); err == nil {
+			t.Fatalf("error expected for path param %s\n", dots)
+		}
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s", r.URL.EscapedPath())
+	}))
+	defer ts.Close()
+
+	b, _ := NewBaseUrl2(ts.URL)
+	_, content, resp, err := b.Http("/users/{id}", PathParams(pathParams))
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if string(content) != "/users/a%2Fb%3Fc" || UriTemplate(resp.Request) != "/users/{id}" {
+		t.Fatalf("unexpected response: %s, %s\n", content, UriTemplate(resp.Request))
+	}
+}
+
+func Test_MultiValuedHeaders(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s|%s|%s|%s", r.Host, strings.Join(r.Header.Values("Accept"), ","), strings.Join(r.Header.Values("X-Forwarded-For"), ","), r.Header.Get("X-Param"))
+	}))
+	defer ts.Close()
+
+	_, content, _, err := Http(ts.URL,
+		Headers(map[string]string{"Accept": "text/html", "X-Param": "v1"}),
+		AddHeader("Accept", "application/json"),
+		HttpHeader(http.Header{"X-Forwarded-For": []string{"1.1.1.1", "2.2.2.2"}}),
+		SetHeader("x-param", "v2"),
+		Host("example.com"),
+	)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if string(content) != "example.com|text/html,application/json|1.1.1.1,2.2.2.2|v2" {
+		t.Fatalf("unexpected response: %s\n", content)
+	}
+}
+
+func Test_AnyMethod(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s|%s|%s", r.Method, r.URL.RawQuery, b)
+	}))
+	defer ts.Close()
+
+	cases := []struct{
+		options []Option
+		expected string
+	}{
+		{[]Option{M("options"), Params(map[string]string{"a": "b"})}, "OPTIONS|a=b|"},
+		{[]Option{M("PROPFIND"), Params(map[string]string{"a": "b"})}, "PROPFIND||a=b"},
+		{[]Option{M("PROPFIND"), Params(map[string]string{"a": "b"}), ParamsInQuery()}, "PROPFIND|a=b|"},
+		{[]Option{M("GET"), Params(map[string]string{"a": "b"}), ParamsInBody()}, "GET||a=b"},
+	}
+	for _, c := range cases {
+		_, content, _, err := Http(ts.URL, c.options...)
+		if err != nil {
+			t.Fatalf("%v\n", err)
+		}
+		if string(content) != c.expected {
+			t.Fatalf("expected %s, got %s\n", c.expected, content)
+		}
+	}
+
+	if status, _, _, err := Http(ts.URL, M("BAD METHOD")); err == nil || status != http.StatusMethodNotAllowed {
+		t.Fatalf("invalid method expected to be rejected\n")
+	}
+}
@@ -1,8 +1,9 @@
 module github.com/rosbit/gnet
 
-go 1.16
+go 1.18
 
 require (
 	github.com/mroth/weightedrand v0.4.1
 	github.com/rosbit/reader-logger v0.1.1
+	golang.org/x/net v0.10.0
 )
@@ -0,0 +1,199 @@
+package gnet
+
+import (
+	"encoding/json"
+	"sync"
+	"fmt"
+	"io"
+)
+
+// format of a response body containing many JSON values
+type JSONStreamFormat int
+const (
+	NDJSON    JSONStreamFormat = iota // values separated by newlines (JSON Lines)
+	JSONArray                         // a top-level JSON array
+)
+
+// returned by a handler to stop iterating without error
+var ErrStopIteration = fmt.Errorf("stop iteration")
+
+type FnCallIter func(url string, format JSONStreamFormat, options ...Option) (status int, it *JSONIterator, err error)
+
+// the items of response body are decoded one by one, it.Close() must be called.
+func HttpCallIter(url string, format JSONStreamFormat, options ...Option) (int, *JSONIterator, error) {
+	return callGNetIter(url, http_i, format, options...)
+}
+
+func JSONCallIter(url string, format JSONStreamFormat, options ...Option) (int, *JSONIterator, error) {
+	return callGNetIter(url, json_i, format, options...)
+}
+
+type FnCallStream func(url string, format JSONStreamFormat, handler func(decode func(v interface{}) error) error, options ...Option) (status int, err error)
+
+// handler is called for every item with a function to decode it, returning ErrStopIteration
+// or any error from handler stops the iteration and closes the body.
+func HttpCallStream(url string, format JSONStreamFormat, handler func(decode func(v interface{}) error) error, options ...Option) (int, error) {
+	return callGNetStream(url, http_i, format, handler, options...)
+}
+
+func JSONCallStream(url string, format JSONStreamFormat, handler func(decode func(v interface{}) error) error, options ...Option) (int, error) {
+	return callGNetStream(url, json_i, format, handler, options...)
+}
+
+func callGNetIter(url string, fnCall httpFunc_i, format JSONStreamFormat, options ...Option) (int, *JSONIterator, error) {
+	option := getOptions(options...)
+	option.dontReadRespBody = true
+	option.streaming = true
+	status, _, resp, err := fnCall(url, option)
+	if err != nil {
+		return status, nil, err
+	}
+	return status, newJSONIterator(resp.Body, format, option.bodyLogger), nil
+}
+
+func callGNetStream(url string, fnCall httpFunc_i, format JSONStreamFormat, handler func(decode func(v interface{}) error) error, options ...Option) (int, error) {
+	status, it, err := callGNetIter(url, fnCall, format, options...)
+	if err != nil {
+		return status, err
+	}
+	defer it.Close()
+
+	for it.Next() {
+		if err = handler(it.Decode); err != nil {
+			if err == ErrStopIteration {
+				return status, nil
+			}
+			return status, err
+		}
+	}
+	return status, it.Err()
+}
+
+// ---- iterator ----
+
+// JSONIterator decodes items of a NDJSON stream or a top-level JSON array.
+//   for it.Next() {
+//       if err := it.Decode(&item); err != nil { ... }
+//   }
+//   if err := it.Err(); err != nil { ... }
+type JSONIterator struct {
+	body io.ReadCloser
+	dec *json.Decoder
+	format JSONStreamFormat
+	deferFunc func()
+
+	started bool
+	pending bool // an item is not decoded by the caller
+	err error
+
+	closeOnce sync.Once
+	closed chan struct{}
+}
+
+func newJSONIterator(body io.ReadCloser, format JSONStreamFormat, logger io.Writer) *JSONIterator {
+	respBody, deferFunc := bodyLogger(body, logger)
+	return &JSONIterator{
+		body: body,
+		dec: json.NewDecoder(respBody),
+		format: format,
+		deferFunc: deferFunc,
+		closed: make(chan struct{}),
+	}
+}
+
+// Next prepares the next item to be decoded by Decode(), false is returned at the end or error.
+func (it *JSONIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if it.pending {
+		// skip the item not decoded
+		var raw json.RawMessage
+		if it.err = it.dec.Decode(&raw); it.err != nil {
+			return false
+		}
+		it.pending = false
+	}
+	if !it.started {
+		it.started = true
+		if it.format == JSONArray {
+			if it.err = it.expectDelim('['); it.err != nil {
+				return false
+			}
+		}
+	}
+
+	if !it.dec.More() {
+		if it.format == JSONArray {
+			if it.err = it.expectDelim(']'); it.err != nil {
+				return false
+			}
+		}
+		it.err = io.EOF
+		return false
+	}
+	it.pending = true
+	return true
+}
+
+func (it *JSONIterator) Decode(v interface{}) error {
+	if !it.pending {
+		return fmt.Errorf("Decode() must be called after Next() returns true")
+	}
+	it.pending = false
+	if err := it.dec.Decode(v); err != nil {
+		it.err = err
+		return err
+	}
+	return nil
+}
+
+// the error stopping the iteration, nil at the end of items
+func (it *JSONIterator) Err() error {
+	if it.err == io.EOF {
+		return nil
+	}
+	return it.err
+}
+
+// Chan returns a channel of raw items, which is closed at the end of items or after Close()
+// is called. Err() can be checked after the channel is closed.
+func (it *JSONIterator) Chan() <-chan json.RawMessage {
+	ch := make(chan json.RawMessage)
+	go func() {
+		defer close(ch)
+		for it.Next() {
+			var raw json.RawMessage
+			if err := it.Decode(&raw); err != nil {
+				return
+			}
+			select {
+			case ch <- raw:
+			case <-it.closed:
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// close the body, it can be called to stop the iteration early.
+func (it *JSONIterator) Close() (err error) {
+	it.closeOnce.Do(func() {
+		close(it.closed)
+		err = it.body.Close()
+		it.deferFunc()
+	})
+	return
+}
+
+func (it *JSONIterator) expectDelim(delim json.Delim) error {
+	t, err := it.dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := t.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("%v expected, but got %v", delim, t)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package gnet
+
+import (
+	"testing"
+	"net/http"
+	"net/http/httptest"
+	"encoding/json"
+	"fmt"
+)
+
+func TestJSONStream(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ndjson":
+			for i:=1; i<=5; i++ {
+				fmt.Fprintf(w, "{\"id\":%d}\n", i)
+			}
+		case "/array":
+			fmt.Fprintf(w, `[{"id":1}, {"id":2}, {"id":3}, {"id":4}, {"id":5}]`)
+		}
+	}))
+	defer ts.Close()
+
+	type item struct {
+		Id int `json:"id"`
+	}
+	for path, format := range map[string]JSONStreamFormat{"/ndjson": NDJSON, "/array": JSONArray} {
+		sum := 0
+		_, err := HttpCallStream(ts.URL + path, format, func(decode func(interface{}) error) error {
+			var i item
+			if err := decode(&i); err != nil {
+				return err
+			}
+			sum += i.Id
+			if i.Id == 4 {
+				return ErrStopIteration
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%s: %v\n", path, err)
+		}
+		if sum != 10 {
+			t.Fatalf("%s: expected sum 10, got %d\n", path, sum)
+		}
+
+		_, it, err := HttpCallIter(ts.URL + path, format)
+		if err != nil {
+			t.Fatalf("%s: %v\n", path, err)
+		}
+		sum = 0
+		for raw := range it.Chan() {
+			var i item
+			json.Unmarshal(raw, &i)
+			sum += i.Id
+		}
+		it.Close()
+		if it.Err() != nil || sum != 15 {
+			t.Fatalf("%s: expected sum 15, got %d, %v\n", path, sum, it.Err())
+		}
+	}
+
+	_, it, _ := HttpCallIter(ts.URL + "/ndjson", JSONArray)
+	defer it.Close()
+	if it.Next() || it.Err() == nil {
+		t.Fatalf("error expected for a non-array body\n")
+	}
+}